@@ -0,0 +1,91 @@
+package jsonschema
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Annotation is the value of an annotation keyword, like title or default,
+// of a schema that an instance passed.
+type Annotation struct {
+	Keyword string
+	Value   any
+}
+
+// annotationKeywords maps the collected annotation keywords to the version
+// of the dialect that introduced them.
+var annotationKeywords = map[string]int{
+	"title":       4,
+	"description": 4,
+	"default":     4,
+	"examples":    6,
+	"readOnly":    7,
+	"writeOnly":   7,
+	"deprecated":  2019,
+}
+
+// ValidateWithAnnotations validates target against schema with the default
+// settings of NewValidator and returns the collected annotations.
+func ValidateWithAnnotations(target any, schema any) (map[string][]Annotation, error) {
+	return NewValidator().ValidateWithAnnotations(target, schema)
+}
+
+// ValidateWithAnnotations validates target against schema like Validate and
+// also returns the annotations of every schema the instance passed, keyed by
+// the JSON Pointer of the instance location they apply to, "" being the root.
+// Annotations of failing subschemas, like the other branches of a oneOf, are
+// not collected. No annotations are returned when validation fails.
+func (v *Validator) ValidateWithAnnotations(target any, schema any) (map[string][]Annotation, error) {
+	validatedTarget, err := validateTarget(reflect.ValueOf(target))
+	if err != nil {
+		return nil, err
+	}
+
+	validatedSchema, err := validateSchema(v, reflect.ValueOf(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	e := newEvaluated()
+	e.collect = true
+
+	res, evaluateErr := e.evaluate(validatedTarget, validatedSchema)
+	if evaluateErr != nil {
+		return nil, evaluateErr
+	}
+
+	annotations := res.annotations
+	if annotations == nil {
+		annotations = make(map[string][]Annotation)
+	}
+
+	for _, values := range annotations {
+		sort.SliceStable(values, func(i, j int) bool {
+			return values[i].Keyword < values[j].Keyword
+		})
+	}
+
+	return annotations, nil
+}
+
+func getAnnotations(d *dialect, values map[string]any) []Annotation {
+	var res []Annotation
+
+	for keyword, version := range annotationKeywords {
+		value, ok := values[keyword]
+		if !ok || d.version < version || !d.allows(keyword) {
+			continue
+		}
+
+		res = append(res, Annotation{
+			Keyword: keyword,
+			Value:   value,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Keyword < res[j].Keyword
+	})
+
+	return res
+}
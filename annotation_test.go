@@ -0,0 +1,129 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateWithAnnotations(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		instance string
+		want     map[string][]Annotation
+	}{
+		{
+			name:     "nested instance locations",
+			schema:   `{"title": "root", "properties": {"a": {"title": "A", "items": {"description": "item"}}}}`,
+			instance: `{"a": [1, 2]}`,
+			want: map[string][]Annotation{
+				"":     {{Keyword: "title", Value: "root"}},
+				"/a":   {{Keyword: "title", Value: "A"}},
+				"/a/0": {{Keyword: "description", Value: "item"}},
+				"/a/1": {{Keyword: "description", Value: "item"}},
+			},
+		},
+		{
+			name:     "escaped property names",
+			schema:   `{"properties": {"a/b~c": {"title": "x"}}}`,
+			instance: `{"a/b~c": 1}`,
+			want: map[string][]Annotation{
+				"/a~1b~0c": {{Keyword: "title", Value: "x"}},
+			},
+		},
+		{
+			name:     "failing anyOf branch",
+			schema:   `{"anyOf": [{"type": "string", "title": "S"}, {"type": "integer", "title": "I"}, {"title": "Any"}]}`,
+			instance: `1`,
+			want: map[string][]Annotation{
+				"": {{Keyword: "title", Value: "I"}, {Keyword: "title", Value: "Any"}},
+			},
+		},
+		{
+			name:     "failing oneOf branch",
+			schema:   `{"oneOf": [{"type": "string", "title": "S"}, {"type": "integer", "title": "I"}]}`,
+			instance: `1`,
+			want: map[string][]Annotation{
+				"": {{Keyword: "title", Value: "I"}},
+			},
+		},
+		{
+			name:     "failing if",
+			schema:   `{"if": {"type": "string", "title": "If"}, "then": {"title": "Then"}, "else": {"title": "Else"}}`,
+			instance: `1`,
+			want: map[string][]Annotation{
+				"": {{Keyword: "title", Value: "Else"}},
+			},
+		},
+		{
+			name:     "passing if",
+			schema:   `{"if": {"type": "string", "title": "If"}, "then": {"description": "Then"}, "else": {"title": "Else"}}`,
+			instance: `"a"`,
+			want: map[string][]Annotation{
+				"": {{Keyword: "description", Value: "Then"}, {Keyword: "title", Value: "If"}},
+			},
+		},
+		{
+			name:     "failing property of a failing branch",
+			schema:   `{"anyOf": [{"properties": {"a": {"title": "A", "type": "string"}}}, {"title": "Any"}]}`,
+			instance: `{"a": 1}`,
+			want: map[string][]Annotation{
+				"": {{Keyword: "title", Value: "Any"}},
+			},
+		},
+		{
+			name:     "$ref target",
+			schema:   `{"$defs": {"a": {"title": "A", "default": 0}}, "$ref": "#/$defs/a", "description": "root"}`,
+			instance: `1`,
+			want: map[string][]Annotation{
+				"": {{Keyword: "default", Value: float64(0)}, {Keyword: "description", Value: "root"}, {Keyword: "title", Value: "A"}},
+			},
+		},
+		{
+			name:     "siblings of $ref in draft-07",
+			schema:   `{"$schema": "http://json-schema.org/draft-07/schema#", "definitions": {"a": {"title": "A"}}, "$ref": "#/definitions/a", "description": "ignored"}`,
+			instance: `1`,
+			want: map[string][]Annotation{
+				"": {{Keyword: "title", Value: "A"}},
+			},
+		},
+		{
+			name:     "keywords newer than the dialect",
+			schema:   `{"$schema": "http://json-schema.org/draft-07/schema#", "title": "A", "deprecated": true}`,
+			instance: `1`,
+			want: map[string][]Annotation{
+				"": {{Keyword: "title", Value: "A"}},
+			},
+		},
+		{
+			name:     "no annotations",
+			schema:   `{"type": "integer"}`,
+			instance: `1`,
+			want:     map[string][]Annotation{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateWithAnnotations(tt.instance, tt.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWithAnnotationsFailing(t *testing.T) {
+	got, err := ValidateWithAnnotations(`"a"`, `{"title": "root", "type": "integer"}`)
+	if err == nil {
+		t.Fatal("want error")
+	}
+
+	if got != nil {
+		t.Errorf("got %v, want no annotations", got)
+	}
+}
@@ -0,0 +1,639 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// compiler keeps the state shared by all subschemas while one schema is
+// compiled. Every subschema is cached by its document and JSON Pointer, so a
+// $ref to a schema that is still being compiled, like a recursive tree node,
+// gets the same *Schema back instead of compiling it again. Documents loaded
+// by $ref are cached too, so each of them is fetched and compiled once.
+// Every document has its own dialect, declared by the $schema of its root or
+// the default of the validator.
+type compiler struct {
+	validator *Validator
+	documents map[string]any
+	dialects  map[string]*dialect
+	resources map[string]resource
+	anchors   map[string]resource
+	dynamic   map[string]map[string]resource
+	schemas   map[string]*Schema
+
+	document string
+	base     string
+	location []string
+	resource *Schema
+	dialect  *dialect
+	current  *Schema
+}
+
+// resource is a schema that can be referenced by URI: a document root, a
+// subschema with its own $id or a subschema with an anchor.
+type resource struct {
+	document string
+	location []string
+}
+
+func newCompiler(v *Validator) *compiler {
+	return &compiler{
+		validator: v,
+		documents: make(map[string]any),
+		dialects:  make(map[string]*dialect),
+		resources: make(map[string]resource),
+		anchors:   make(map[string]resource),
+		dynamic:   make(map[string]map[string]resource),
+		schemas:   make(map[string]*Schema),
+	}
+}
+
+// addDocument registers root as the document loaded from uri and indexes the
+// $id and anchors of every subschema in it.
+func (c *compiler) addDocument(uri string, root any) error {
+	c.documents[uri] = root
+	c.resources[uri] = resource{document: uri}
+
+	d, err := c.dialectOf(uri, root)
+	if err != nil {
+		delete(c.documents, uri)
+		delete(c.resources, uri)
+		return err
+	}
+
+	c.dialects[uri] = d
+
+	c.indexResources(d, uri, uri, nil, root)
+
+	return nil
+}
+
+func (c *compiler) indexResources(d *dialect, document, base string, location []string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if id, ok := schemaID(d, v); ok {
+			base = resolveURI(base, id)
+			uri, _, _ := strings.Cut(base, "#")
+
+			c.resources[uri] = resource{
+				document: document,
+				location: append([]string(nil), location...),
+			}
+		}
+
+		c.indexAnchors(d, document, base, location, v)
+
+		for key, value := range v {
+			//values of these keywords are data, not schemas
+			switch key {
+			case "enum", "const", "default", "examples":
+				continue
+			}
+
+			c.indexResources(d, document, base, append(location, key), value)
+		}
+	case []any:
+		for i, value := range v {
+			c.indexResources(d, document, base, append(location, strconv.Itoa(i)), value)
+		}
+	}
+}
+
+// indexAnchors registers the plain name anchors of a schema: the $id made of
+// a fragment only before 2019-09, $anchor since 2019-09 and $dynamicAnchor
+// since 2020-12.
+func (c *compiler) indexAnchors(d *dialect, document, base string, location []string, values map[string]any) {
+	uri, _, _ := strings.Cut(base, "#")
+	res := resource{
+		document: document,
+		location: append([]string(nil), location...),
+	}
+
+	if d.version < 2019 {
+		if id, ok := values[d.id].(string); ok && strings.HasPrefix(id, "#") {
+			c.anchors[uri+id] = res
+		}
+
+		return
+	}
+
+	if anchor, ok := values["$anchor"].(string); ok {
+		c.anchors[uri+"#"+anchor] = res
+	}
+
+	if anchor, ok := values["$dynamicAnchor"].(string); ok && d.version >= 2020 {
+		c.anchors[uri+"#"+anchor] = res
+
+		if c.dynamic[uri] == nil {
+			c.dynamic[uri] = make(map[string]resource)
+		}
+
+		c.dynamic[uri][anchor] = res
+	}
+}
+
+// load fetches the document at uri unless it is already known.
+func (c *compiler) load(uri string) error {
+	if _, ok := c.resources[uri]; ok {
+		return nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+
+	var data string
+
+	switch u.Scheme {
+	case "http", "https":
+		data, err = getJSONFromUrl(uri)
+	case "file":
+		data, err = getJSONFromFile(filepath.FromSlash(u.Path))
+	case "":
+		data, err = getJSONFromFile(uri)
+	default:
+		return errors.New("unsupported $ref scheme: " + uri)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var root any
+	err = json.Unmarshal([]byte(data), &root)
+	if err != nil {
+		return err
+	}
+
+	return c.addDocument(uri, root)
+}
+
+// compileDocument compiles the root of the document at uri and checks that no
+// schema applies itself to the same instance, through $ref or in-place
+// applicators like allOf, which would never stop at validation.
+func (c *compiler) compileDocument(uri string) (*Schema, error) {
+	res, err := c.compileAt(uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[*Schema]int, len(c.schemas))
+
+	//visit returns a schema on a cycle reachable from schema, if any
+	var visit func(schema *Schema) *Schema
+	visit = func(schema *Schema) *Schema {
+		switch state[schema] {
+		case visiting:
+			return schema
+		case visited:
+			return nil
+		}
+
+		state[schema] = visiting
+
+		for _, next := range c.inPlaceOf(schema) {
+			if cycle := visit(next); cycle != nil {
+				return cycle
+			}
+		}
+
+		state[schema] = visited
+		return nil
+	}
+
+	keys := make([]string, 0, len(c.schemas))
+	keyOf := make(map[*Schema]string, len(c.schemas))
+	for key := range c.schemas {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := keyOf[c.schemas[key]]; !ok {
+			keyOf[c.schemas[key]] = key
+		}
+	}
+
+	for _, key := range keys {
+		if cycle := visit(c.schemas[key]); cycle != nil {
+			return nil, errors.New("schema cycle at " + keyOf[cycle])
+		}
+	}
+
+	return res, nil
+}
+
+// inPlaceOf returns the schemas that apply to the same instance as schema.
+// $dynamicRef and $recursiveRef can resolve to any schema with a matching
+// anchor depending on the dynamic scope, so all of them count.
+func (c *compiler) inPlaceOf(schema *Schema) []*Schema {
+	res := append([]*Schema(nil), schema.inPlace...)
+
+	if schema.ref != nil {
+		res = append(res, schema.ref)
+	}
+
+	if r := schema.dynamicRef; r != nil {
+		res = append(res, r.schema)
+
+		for _, other := range c.schemas {
+			switch {
+			case r.anchor != "" && other.dynamicAnchors[r.anchor] != nil:
+				res = append(res, other.dynamicAnchors[r.anchor])
+			case r.recursive && other.recursiveAnchor:
+				res = append(res, other)
+			}
+		}
+	}
+
+	return res
+}
+
+// compileAt compiles the schema found at location in document.
+func (c *compiler) compileAt(document string, location []string) (*Schema, error) {
+	value, err := walkPointer(c.documents[document], location)
+	if err != nil {
+		return nil, err
+	}
+
+	base, root := c.baseOf(document, location)
+
+	prevDocument, prevBase, prevLocation, prevResource, prevDialect := c.document, c.base, c.location, c.resource, c.dialect
+	defer func() {
+		c.document, c.base, c.location, c.resource, c.dialect = prevDocument, prevBase, prevLocation, prevResource, prevDialect
+	}()
+
+	c.dialect = c.dialects[document]
+
+	//a schema inside a resource needs the resource root for the dynamic scope
+	c.resource = nil
+	if root != nil {
+		c.resource, _ = c.compileAt(document, root)
+	}
+
+	c.document = document
+	c.base = base
+	c.location = nil
+
+	return c.compile(value, location...)
+}
+
+// baseOf returns the base URI in effect for the schema at location, taking
+// the $id of every enclosing schema into account, and the location of the
+// root of the schema resource it belongs to. The root is nil when the schema
+// starts a resource itself.
+func (c *compiler) baseOf(document string, location []string) (string, []string) {
+	base := document
+	root := []string{}
+	value := c.documents[document]
+	d := c.dialects[document]
+
+	for i, token := range location {
+		if v, ok := value.(map[string]any); ok {
+			if id, ok := schemaID(d, v); ok {
+				base = resolveURI(base, id)
+				root = location[:i]
+			}
+		}
+
+		value, _ = walkPointer(value, []string{token})
+	}
+
+	if v, ok := value.(map[string]any); ok {
+		if _, ok := schemaID(d, v); ok {
+			root = nil
+		}
+	}
+
+	if len(location) == 0 {
+		root = nil
+	}
+
+	return base, root
+}
+
+// compile compiles value found at the current location extended by tokens.
+func (c *compiler) compile(value any, tokens ...string) (*Schema, error) {
+	c.location = append(c.location, tokens...)
+	defer func() {
+		c.location = c.location[:len(c.location)-len(tokens)]
+	}()
+
+	key := c.document + "#" + c.pointer()
+	if schema, ok := c.schemas[key]; ok {
+		return schema, nil
+	}
+
+	//true accepts and false rejects any value
+	if v, ok := value.(bool); ok {
+		res := &Schema{falseSchema: !v}
+		c.schemas[key] = res
+
+		return res, nil
+	}
+
+	values, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("schema at " + key + " must be object or boolean")
+	}
+
+	var res *Schema = &Schema{}
+	c.schemas[key] = res
+
+	//the document root and every schema with $id start a new resource
+	_, isRoot := schemaID(c.dialect, values)
+	isRoot = isRoot || len(c.location) == 0
+
+	if isRoot {
+		base, resource := c.base, c.resource
+		defer func() {
+			c.base, c.resource = base, resource
+		}()
+
+		if id, ok := schemaID(c.dialect, values); ok {
+			c.base = resolveURI(c.base, id)
+		}
+
+		c.resource = res
+	}
+
+	res.resource = c.resource
+
+	current := c.current
+	c.current = res
+	err := fillSchemaFromJSON(c, res, values)
+	c.current = current
+	if err != nil {
+		delete(c.schemas, key)
+		return nil, err
+	}
+
+	if isRoot {
+		err = c.compileDynamicAnchors(res)
+		if err != nil {
+			delete(c.schemas, key)
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// compileInPlace compiles a subschema that applies to the same instance as
+// the schema being compiled, like a schema of allOf, and records it for the
+// cycle check.
+func (c *compiler) compileInPlace(value any, tokens ...string) (*Schema, error) {
+	parent := c.current
+
+	res, err := c.compile(value, tokens...)
+	if err != nil {
+		return nil, err
+	}
+
+	parent.inPlace = append(parent.inPlace, res)
+
+	return res, nil
+}
+
+func (c *compiler) pointer() string {
+	var res strings.Builder
+
+	for _, token := range c.location {
+		res.WriteString("/")
+		res.WriteString(escapePointer(token))
+	}
+
+	return res.String()
+}
+
+func escapePointer(token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	return strings.ReplaceAll(token, "/", "~1")
+}
+
+// resolve compiles the schema that ref points to. ref is resolved against the
+// current base URI, so it can point inside the same document, like
+// #/$defs/address or #address, or to another file or URL, like
+// common.json#/$defs/money.
+func (c *compiler) resolve(ref string) (*Schema, error) {
+	res, err := c.locate(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.compileAt(res.document, res.location)
+}
+
+// locate finds where the schema that ref points to is. The fragment is either
+// a JSON Pointer or a plain name anchor.
+func (c *compiler) locate(ref string) (resource, error) {
+	target := resolveURI(c.base, ref)
+	uri, fragment, _ := strings.Cut(target, "#")
+
+	res, err := c.lookup(uri, ref)
+	if err != nil {
+		return resource{}, err
+	}
+
+	fragment, err = url.PathUnescape(fragment)
+	if err != nil {
+		return resource{}, err
+	}
+
+	if fragment != "" && !strings.HasPrefix(fragment, "/") {
+		anchor, ok := c.anchors[uri+"#"+fragment]
+		if !ok {
+			return resource{}, errors.New("$ref " + ref + " not found: no anchor " + fragment)
+		}
+
+		return anchor, nil
+	}
+
+	tokens, err := splitPointer(fragment)
+	if err != nil {
+		return resource{}, err
+	}
+
+	location := append(append([]string(nil), res.location...), tokens...)
+	if _, err := walkPointer(c.documents[res.document], location); err != nil {
+		return resource{}, errors.New("$ref " + ref + " not found: " + err.Error())
+	}
+
+	return resource{
+		document: res.document,
+		location: location,
+	}, nil
+}
+
+// lookup finds the schema resource identified by uri, loading it if needed.
+// When the base URI comes from an $id, ref is also tried relative to the
+// location the current document was loaded from. Documents loaded from files
+// look next to the file first, so their $id hosts are only fetched for
+// documents missing there.
+func (c *compiler) lookup(uri, ref string) (resource, error) {
+	if res, ok := c.resources[uri]; ok {
+		return res, nil
+	}
+
+	fallback, _, _ := strings.Cut(resolveURI(c.document, ref), "#")
+	fromFile := strings.HasPrefix(c.document, "file:")
+
+	if fallback != uri && fromFile && c.load(fallback) == nil {
+		return c.alias(uri, fallback), nil
+	}
+
+	err := c.load(uri)
+	if err == nil {
+		return c.resources[uri], nil
+	}
+
+	if fallback == uri || fromFile || c.load(fallback) != nil {
+		return resource{}, errors.New("failed to load $ref " + ref + ": " + err.Error())
+	}
+
+	return c.alias(uri, fallback), nil
+}
+
+// alias makes the resource loaded from fallback reachable from uri.
+func (c *compiler) alias(uri, fallback string) resource {
+	c.resources[uri] = c.resources[fallback]
+	c.aliasAnchors(c.resources[fallback], uri)
+
+	return c.resources[uri]
+}
+
+// aliasAnchors makes the anchors of res, indexed under the URIs it was loaded
+// from or identified by, reachable from uri too.
+func (c *compiler) aliasAnchors(res resource, uri string) {
+	aliases := make(map[string]resource)
+
+	for key, other := range c.resources {
+		if key == uri || other.document != res.document || !slices.Equal(other.location, res.location) {
+			continue
+		}
+
+		for anchor, target := range c.anchors {
+			if name, ok := strings.CutPrefix(anchor, key+"#"); ok {
+				aliases[uri+"#"+name] = target
+			}
+		}
+
+		if dynamic, ok := c.dynamic[key]; ok && c.dynamic[uri] == nil {
+			c.dynamic[uri] = dynamic
+		}
+	}
+
+	for anchor, target := range aliases {
+		if _, ok := c.anchors[anchor]; !ok {
+			c.anchors[anchor] = target
+		}
+	}
+}
+
+// documentURI returns the URI a schema given to Validate was loaded from, or
+// an empty string for inline JSON.
+func documentURI(str string) string {
+	if u, err := url.ParseRequestURI(str); err == nil && u.Scheme != "" {
+		u.Fragment = ""
+		return u.String()
+	}
+
+	if _, err := os.Stat(str); err != nil {
+		return ""
+	}
+
+	path, err := filepath.Abs(str)
+	if err != nil {
+		return ""
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// schemaID returns the $id of a schema when it changes the base URI. An $id
+// made of a fragment only names a location and leaves the base as is.
+func schemaID(d *dialect, values map[string]any) (string, bool) {
+	id, ok := values[d.id].(string)
+	if !ok || strings.HasPrefix(id, "#") {
+		return "", false
+	}
+
+	return id, true
+}
+
+func resolveURI(base, ref string) string {
+	//inline schemas have no base, relative refs stay relative to working dir
+	if base == "" {
+		return ref
+	}
+
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return b.ResolveReference(r).String()
+}
+
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("invalid JSON Pointer: " + pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func walkPointer(value any, tokens []string) (any, error) {
+	for _, token := range tokens {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[token]
+			if !ok {
+				return nil, errors.New("no member " + token)
+			}
+
+			value = next
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, errors.New("no index " + token)
+			}
+
+			value = v[i]
+		default:
+			return nil, errors.New("no member " + token)
+		}
+	}
+
+	return value, nil
+}
@@ -0,0 +1,188 @@
+package jsonschema
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDocuments writes documents, by file name, to a temporary directory
+// and returns its path.
+func writeDocuments(t *testing.T, documents map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, document := range documents {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(document), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestRefFallback(t *testing.T) {
+	const money = `"$defs": {"money": {"$anchor": "money", "required": ["amount"]}}`
+
+	tests := []struct {
+		name   string
+		common string
+		ref    string
+	}{
+		{
+			name:   "anchor",
+			common: `{` + money + `}`,
+			ref:    "common.json#money",
+		},
+		{
+			name:   "JSON Pointer",
+			common: `{` + money + `}`,
+			ref:    "common.json#/$defs/money",
+		},
+		{
+			name:   "anchor of a document with its own $id",
+			common: `{"$id": "https://example.invalid/other/common.json", ` + money + `}`,
+			ref:    "common.json#money",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeDocuments(t, map[string]string{
+				"root.json":   `{"$id": "https://example.invalid/schemas/root.json", "$ref": "` + tt.ref + `"}`,
+				"common.json": tt.common,
+			})
+			root := filepath.Join(dir, "root.json")
+
+			if err := Validate(`{"amount": 1}`, root); err != nil {
+				t.Errorf("got %v, want valid", err)
+			}
+
+			err := Validate(`{}`, root)
+			if err == nil || !strings.Contains(err.Error(), "expected: amount") {
+				t.Errorf("got %v, want a missing amount", err)
+			}
+		})
+	}
+}
+
+func TestRefFileFirst(t *testing.T) {
+	var fetched []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = append(fetched, r.URL.Path)
+		w.Write([]byte(`{"required": ["remote"]}`))
+	}))
+	defer server.Close()
+
+	dir := writeDocuments(t, map[string]string{
+		"root.json":  `{"$id": "` + server.URL + `/schemas/root.json", "properties": {"a": {"$ref": "local.json"}, "b": {"$ref": "remote.json"}}}`,
+		"local.json": `{"required": ["local"]}`,
+	})
+	root := filepath.Join(dir, "root.json")
+
+	err := Validate(`{"a": {"local": 1}, "b": {"remote": 1}}`, root)
+	if err != nil {
+		t.Errorf("got %v, want valid", err)
+	}
+
+	err = Validate(`{"a": {"remote": 1}}`, root)
+	if err == nil || !strings.Contains(err.Error(), "expected: local") {
+		t.Errorf("got %v, want a missing local", err)
+	}
+
+	//only the document missing next to root.json is fetched
+	if len(fetched) == 0 {
+		t.Error("remote.json was not fetched")
+	}
+
+	for _, path := range fetched {
+		if path != "/schemas/remote.json" {
+			t.Errorf("fetched %s, want only /schemas/remote.json", path)
+		}
+	}
+}
+
+func TestSchemaCycle(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		//cycle is set when the schema must not compile
+		cycle bool
+	}{
+		{
+			name:   "anyOf",
+			schema: `{"$defs": {"a": {"anyOf": [{"$ref": "#/$defs/a"}]}}, "$ref": "#/$defs/a"}`,
+			cycle:  true,
+		},
+		{
+			name:   "allOf",
+			schema: `{"allOf": [{"$ref": "#"}]}`,
+			cycle:  true,
+		},
+		{
+			name:   "not",
+			schema: `{"not": {"$ref": "#"}}`,
+			cycle:  true,
+		},
+		{
+			name:   "then",
+			schema: `{"if": true, "then": {"$ref": "#"}}`,
+			cycle:  true,
+		},
+		{
+			name:   "dependentSchemas",
+			schema: `{"dependentSchemas": {"a": {"$ref": "#"}}}`,
+			cycle:  true,
+		},
+		{
+			name:   "$ref chain",
+			schema: `{"$defs": {"a": {"$ref": "#/$defs/b"}, "b": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`,
+			cycle:  true,
+		},
+		{
+			name:   "$dynamicRef",
+			schema: `{"$dynamicRef": "#"}`,
+			cycle:  true,
+		},
+		{
+			name:   "$dynamicRef to a $dynamicAnchor",
+			schema: `{"$dynamicAnchor": "node", "anyOf": [{"$dynamicRef": "#node"}]}`,
+			cycle:  true,
+		},
+		{
+			name:   "$recursiveRef",
+			schema: `{"$schema": "https://json-schema.org/draft/2019-09/schema", "$recursiveAnchor": true, "allOf": [{"$recursiveRef": "#"}]}`,
+			cycle:  true,
+		},
+		{
+			name:   "$dynamicRef in properties",
+			schema: `{"$dynamicAnchor": "node", "properties": {"child": {"$dynamicRef": "#node"}}}`,
+		},
+		{
+			name:   "properties",
+			schema: `{"properties": {"child": {"$ref": "#"}}}`,
+		},
+		{
+			name:   "items in anyOf",
+			schema: `{"anyOf": [{"type": "integer"}, {"items": {"$ref": "#"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(`1`, tt.schema)
+
+			switch {
+			case tt.cycle && (err == nil || !strings.Contains(err.Error(), "schema cycle at")):
+				t.Errorf("got %v, want a schema cycle", err)
+			case !tt.cycle && err != nil:
+				t.Errorf("got %v, want valid", err)
+			}
+		})
+	}
+}
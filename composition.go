@@ -0,0 +1,122 @@
+package jsonschema
+
+import (
+	"errors"
+	"strconv"
+)
+
+func allOf(c *compiler, value any) (validateFunc, error) {
+	schemas, err := subschemas(c, "allOf", value)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(a any, e *evaluated) *Error {
+		for _, schema := range schemas {
+			res, err := e.evaluate(a, schema)
+			if err != nil {
+				return err
+			}
+
+			e.merge(res)
+		}
+
+		return nil
+	}, nil
+}
+
+// anyOf checks every subschema, even after a match, so that the annotations
+// of all matching subschemas are collected.
+func anyOf(c *compiler, value any) (validateFunc, error) {
+	schemas, err := subschemas(c, "anyOf", value)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(a any, e *evaluated) *Error {
+		var matched int
+		causes := make([]*Error, 0, len(schemas))
+
+		for _, schema := range schemas {
+			res, err := e.evaluate(a, schema)
+			if err == nil {
+				matched++
+				e.merge(res)
+			}
+
+			causes = append(causes, err)
+		}
+
+		if matched > 0 {
+			return nil
+		}
+
+		return NewError("at least one match", 0).SetCauses(causes)
+	}, nil
+}
+
+func oneOf(c *compiler, value any) (validateFunc, error) {
+	schemas, err := subschemas(c, "oneOf", value)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(a any, e *evaluated) *Error {
+		var matched int
+		var match *evaluated
+		causes := make([]*Error, 0, len(schemas))
+
+		for _, schema := range schemas {
+			res, err := e.evaluate(a, schema)
+			if err == nil {
+				matched++
+				match = res
+			}
+
+			causes = append(causes, err)
+		}
+
+		if matched == 1 {
+			e.merge(match)
+			return nil
+		}
+
+		return NewError("exactly one match", matched).SetCauses(causes)
+	}, nil
+}
+
+func not(c *compiler, value any) (validateFunc, error) {
+	schema, err := c.compileInPlace(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(a any, e *evaluated) *Error {
+		err := e.validate(a, schema)
+		if err != nil {
+			return nil
+		}
+
+		return NewError("not to match", a)
+	}, nil
+}
+
+func subschemas(c *compiler, name string, value any) ([]*Schema, error) {
+	values, ok := value.([]any)
+	if !ok || len(values) == 0 {
+		return nil, errors.New(name + " requires non-empty array of schemas")
+	}
+
+	res := make([]*Schema, 0, len(values))
+
+	for i, value := range values {
+		schema, err := c.compileInPlace(value, strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, schema)
+	}
+
+	return res, nil
+}
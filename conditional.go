@@ -0,0 +1,73 @@
+package jsonschema
+
+// condition is the compiled form of if/then/else. The result of ifSchema
+// decides whether thenSchema or elseSchema applies; either of them can be nil.
+type condition struct {
+	ifSchema   *Schema
+	thenSchema *Schema
+	elseSchema *Schema
+}
+
+func getCondition(c *compiler, values map[string]any) (*condition, error) {
+	raw, ok := values["if"]
+	//then and else are ignored without if
+	if !ok {
+		return nil, nil
+	}
+
+	ifSchema, err := c.compileInPlace(raw, "if")
+	if err != nil {
+		return nil, err
+	}
+
+	res := &condition{
+		ifSchema: ifSchema,
+	}
+
+	if raw, ok := values["then"]; ok {
+		res.thenSchema, err = c.compileInPlace(raw, "then")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if raw, ok := values["else"]; ok {
+		res.elseSchema, err = c.compileInPlace(raw, "else")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (c *condition) validate(target any, e *evaluated) *Error {
+	res, err := e.evaluate(target, c.ifSchema)
+	if err == nil {
+		e.merge(res)
+
+		if c.thenSchema == nil {
+			return nil
+		}
+
+		res, err := e.evaluate(target, c.thenSchema)
+		if err != nil {
+			return err.SetName("then")
+		}
+
+		e.merge(res)
+		return nil
+	}
+
+	if c.elseSchema == nil {
+		return nil
+	}
+
+	res, err = e.evaluate(target, c.elseSchema)
+	if err != nil {
+		return err.SetName("else")
+	}
+
+	e.merge(res)
+	return nil
+}
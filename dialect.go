@@ -0,0 +1,224 @@
+package jsonschema
+
+import (
+	"errors"
+	"strings"
+)
+
+// Dialect is the meta-schema URI a schema declares with $schema.
+type Dialect string
+
+const (
+	Draft4    Dialect = "http://json-schema.org/draft-04/schema#"
+	Draft6    Dialect = "http://json-schema.org/draft-06/schema#"
+	Draft7    Dialect = "http://json-schema.org/draft-07/schema#"
+	Draft2019 Dialect = "https://json-schema.org/draft/2019-09/schema"
+	Draft2020 Dialect = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// dialect holds the keywords of one JSON Schema version. version is the
+// draft number for draft-04 to draft-07 and the year for later ones, so
+// versions compare in release order. id is the keyword that sets the base
+// URI, which draft-04 spells without $. keywords is set for dialects defined
+// by $vocabulary and holds the enabled keywords, nil enables all of them.
+// annotateFormat is set when the $vocabulary lists no format assertion
+// vocabulary.
+type dialect struct {
+	version        int
+	id             string
+	keywords       map[string]struct{}
+	annotateFormat bool
+	validations    map[ValueType]map[string]rawValidation
+	common         map[string]rawValidation
+	final          map[string]rawValidation
+}
+
+// dialects maps normalized meta-schema URIs to their dialect. It is filled in
+// init because keywords with subschemas compile them, which refers back to
+// the keyword tables.
+var dialects map[string]*dialect
+
+func init() {
+	dialects = make(map[string]*dialect)
+
+	for uri, version := range map[Dialect]int{
+		Draft4:    4,
+		Draft6:    6,
+		Draft7:    7,
+		Draft2019: 2019,
+		Draft2020: 2020,
+	} {
+		dialects[normalizeDialect(uri)] = newDialect(version)
+	}
+
+	defaultDialect = newDefaultDialect()
+}
+
+// defaultDialect is the dialect of documents without $schema when the
+// validator has no WithDialect. It is 2020-12 that also keeps the draft-07
+// dependencies, array form items and additionalItems, so legacy schemas
+// without $schema keep their meaning.
+var defaultDialect *dialect
+
+func newDefaultDialect() *dialect {
+	res := newDialect(2020)
+
+	res.validations[Object]["dependencies"] = rawValidation{
+		compile: dependencies,
+	}
+
+	res.validations[Array]["items"] = rawValidation{
+		compile:  itemsDefault,
+		optional: []string{"prefixItems"},
+	}
+
+	res.validations[Array]["additionalItems"] = rawValidation{
+		compile:  additionalItems,
+		optional: []string{"items"},
+	}
+
+	return res
+}
+
+func newDialect(version int) *dialect {
+	res := &dialect{
+		version: version,
+		id:      "$id",
+		validations: map[ValueType]map[string]rawValidation{
+			String:  stringValidationFor(version),
+			Integer: integerValidationFor(version),
+			Number:  numberValidationFor(version),
+			Array:   sliceValidationFor(version),
+			Object:  objectValidationFor(version),
+		},
+		common: commonValidationFor(version),
+		final:  finalValidationFor(version),
+	}
+
+	if version < 6 {
+		res.id = "id"
+	}
+
+	return res
+}
+
+func commonValidationFor(version int) map[string]rawValidation {
+	res := map[string]rawValidation{
+		"allOf": {
+			compile: allOf,
+		},
+		"anyOf": {
+			compile: anyOf,
+		},
+		"oneOf": {
+			compile: oneOf,
+		},
+		"not": {
+			compile: not,
+		},
+		"enum": {
+			function: enum,
+		},
+		"const": {
+			function: constant,
+		},
+	}
+
+	if version < 6 {
+		delete(res, "const")
+	}
+
+	return res
+}
+
+// finalValidationFor returns the keywords that depend on what all other
+// keywords evaluated, they exist since 2019-09.
+func finalValidationFor(version int) map[string]rawValidation {
+	if version < 2019 {
+		return nil
+	}
+
+	return map[string]rawValidation{
+		"unevaluatedProperties": {
+			compile: unevaluatedProperties,
+		},
+		"unevaluatedItems": {
+			compile: unevaluatedItems,
+		},
+	}
+}
+
+// allows reports whether keyword is enabled in the dialect.
+func (d *dialect) allows(keyword string) bool {
+	if d.keywords == nil {
+		return true
+	}
+
+	_, ok := d.keywords[keyword]
+	return ok
+}
+
+// dialectOf returns the dialect declared by the $schema of the root of
+// document, or the default of the validator when root doesn't declare one,
+// defaultDialect without WithDialect.
+// A $schema that isn't a known dialect is loaded as a custom meta-schema,
+// which has the dialect of its own $schema limited by its $vocabulary.
+func (c *compiler) dialectOf(document string, root any) (*dialect, error) {
+	uri := string(c.validator.dialect)
+
+	if values, ok := root.(map[string]any); ok {
+		if raw, ok := values["$schema"]; ok {
+			str, ok := raw.(string)
+			if !ok {
+				return nil, errors.New("$schema requires string")
+			}
+
+			uri = resolveURI(document, str)
+		}
+	}
+
+	if uri == "" {
+		return defaultDialect, nil
+	}
+
+	if res, ok := dialects[normalizeDialect(Dialect(uri))]; ok {
+		return res, nil
+	}
+
+	meta, _, _ := strings.Cut(uri, "#")
+
+	err := c.load(meta)
+	if err != nil {
+		return nil, errors.New("unknown $schema " + uri + ": " + err.Error())
+	}
+
+	//a meta-schema still being loaded has no dialect yet
+	res := c.resources[meta]
+	if c.dialects[res.document] == nil {
+		return nil, errors.New("$schema cycle at " + uri)
+	}
+
+	value, _ := walkPointer(c.documents[res.document], res.location)
+
+	values, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("meta-schema " + uri + " must be object")
+	}
+
+	vocabulary, ok := values["$vocabulary"]
+	if !ok {
+		return c.dialects[res.document], nil
+	}
+
+	return vocabularyDialect(c.dialects[res.document], vocabulary)
+}
+
+// normalizeDialect drops the scheme and the empty fragment, so that
+// https://json-schema.org/draft-07/schema and the canonical
+// http://json-schema.org/draft-07/schema# name the same dialect.
+func normalizeDialect(uri Dialect) string {
+	res := strings.TrimSuffix(string(uri), "#")
+	res = strings.TrimPrefix(res, "https://")
+
+	return strings.TrimPrefix(res, "http://")
+}
@@ -0,0 +1,107 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultDialect(t *testing.T) {
+	const (
+		dependencies = `{"dependencies": {"a": ["b"], "c": {"required": ["d"]}}}`
+		tuple        = `{"items": [{"type": "integer"}, {"type": "string"}], "additionalItems": false}`
+	)
+
+	tests := []struct {
+		name     string
+		options  []Option
+		schema   string
+		instance string
+		//err is a part of the expected error, empty for valid instances
+		err string
+	}{
+		{
+			name:     "dependencies",
+			schema:   dependencies,
+			instance: `{"a": 1, "b": 2, "c": 3, "d": 4}`,
+		},
+		{
+			name:     "dependencies with a missing property",
+			schema:   dependencies,
+			instance: `{"a": 1}`,
+			err:      "dependencies",
+		},
+		{
+			name:     "dependencies with a failing schema",
+			schema:   dependencies,
+			instance: `{"c": 1}`,
+			err:      "required",
+		},
+		{
+			name:     "array form items",
+			schema:   tuple,
+			instance: `[1, "a"]`,
+		},
+		{
+			name:     "array form items failing",
+			schema:   tuple,
+			instance: `["a", 1]`,
+			err:      "expected: integer",
+		},
+		{
+			name:     "additionalItems",
+			schema:   tuple,
+			instance: `[1, "a", 2]`,
+			err:      "additionalItems",
+		},
+		{
+			name:     "prefixItems and items",
+			schema:   `{"prefixItems": [{"type": "integer"}], "items": {"type": "string"}}`,
+			instance: `[1, 2]`,
+			err:      "expected: string",
+		},
+		{
+			name:     "array form items with prefixItems",
+			schema:   `{"prefixItems": [{"type": "integer"}], "items": [{"type": "string"}]}`,
+			instance: `[1]`,
+			err:      "items requires schema, tuples use prefixItems",
+		},
+		{
+			name:     "Draft2020 ignores dependencies",
+			options:  []Option{WithDialect(Draft2020)},
+			schema:   dependencies,
+			instance: `{"a": 1}`,
+		},
+		{
+			name:     "Draft2020 rejects array form items",
+			options:  []Option{WithDialect(Draft2020)},
+			schema:   tuple,
+			instance: `[1, "a"]`,
+			err:      "items requires schema, tuples use prefixItems",
+		},
+		{
+			name:     "Draft7",
+			options:  []Option{WithDialect(Draft7)},
+			schema:   tuple,
+			instance: `[1, "a", 2]`,
+			err:      "additionalItems",
+		},
+		{
+			name:     "$schema takes precedence",
+			schema:   `{"$schema": "https://json-schema.org/draft/2020-12/schema", "dependencies": {"a": ["b"]}}`,
+			instance: `{"a": 1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(tt.options...).Validate(tt.instance, tt.schema)
+
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("got %v, want valid", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package jsonschema
+
+import (
+	"errors"
+	"strings"
+)
+
+// dynamicRef is the compiled form of $dynamicRef and $recursiveRef. schema is
+// the statically resolved target; it is used as is unless the target opts in
+// to dynamic resolution with a matching $dynamicAnchor or with
+// $recursiveAnchor, in which case the dynamic scope decides.
+type dynamicRef struct {
+	schema    *Schema
+	anchor    string
+	recursive bool
+}
+
+func (c *compiler) dynamicRef(ref string) (*dynamicRef, error) {
+	target, err := c.locate(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := c.compileAt(target.document, target.location)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *dynamicRef = &dynamicRef{
+		schema: schema,
+	}
+
+	_, fragment, _ := strings.Cut(ref, "#")
+	value, _ := walkPointer(c.documents[target.document], target.location)
+
+	if v, ok := value.(map[string]any); ok && fragment != "" && v["$dynamicAnchor"] == fragment {
+		res.anchor = fragment
+	}
+
+	return res, nil
+}
+
+func (c *compiler) recursiveRef(ref string) (*dynamicRef, error) {
+	if ref != "#" {
+		return nil, errors.New("$recursiveRef supports only #")
+	}
+
+	target, err := c.locate(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := c.compileAt(target.document, target.location)
+	if err != nil {
+		return nil, err
+	}
+
+	value, _ := walkPointer(c.documents[target.document], target.location)
+	v, _ := value.(map[string]any)
+
+	return &dynamicRef{
+		schema:    schema,
+		recursive: v["$recursiveAnchor"] == true,
+	}, nil
+}
+
+// compileDynamicAnchors compiles the $dynamicAnchor subschemas of the
+// resource that root starts, so the dynamic scope can find them.
+func (c *compiler) compileDynamicAnchors(root *Schema) error {
+	uri, _, _ := strings.Cut(c.base, "#")
+
+	for name, anchor := range c.dynamic[uri] {
+		schema, err := c.compileAt(anchor.document, anchor.location)
+		if err != nil {
+			return err
+		}
+
+		if root.dynamicAnchors == nil {
+			root.dynamicAnchors = make(map[string]*Schema)
+		}
+
+		root.dynamicAnchors[name] = schema
+	}
+
+	return nil
+}
+
+// resolve returns the schema to validate against in the given dynamic scope.
+// $dynamicRef takes the outermost resource that defines the anchor.
+// $recursiveRef takes the outermost resource of the innermost unbroken run of
+// resources with $recursiveAnchor.
+func (r *dynamicRef) resolve(scope []*Schema) *Schema {
+	switch {
+	case r.anchor != "":
+		for _, resource := range scope {
+			if schema, ok := resource.dynamicAnchors[r.anchor]; ok {
+				return schema
+			}
+		}
+	case r.recursive:
+		res := r.schema
+		for i := len(scope) - 1; i >= 0 && scope[i].recursiveAnchor; i-- {
+			res = scope[i]
+		}
+
+		return res
+	}
+
+	return r.schema
+}
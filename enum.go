@@ -0,0 +1,88 @@
+package jsonschema
+
+import "errors"
+
+func enum(value any) (func(a any) *Error, error) {
+	values, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("enum requires array")
+	}
+
+	return func(a any) *Error {
+		for _, value := range values {
+			if equal(a, value) {
+				return nil
+			}
+		}
+
+		return NewError(values, a)
+	}, nil
+}
+
+func constant(value any) (func(a any) *Error, error) {
+	return func(a any) *Error {
+		if equal(a, value) {
+			return nil
+		}
+
+		return NewError(value, a)
+	}, nil
+}
+
+// equal reports whether a and b are the same JSON value: numbers are equal by
+// value, so 1 equals 1.0, and objects and arrays are compared deeply.
+func equal(a, b any) bool {
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		return ok && x == y
+	}
+
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
@@ -1,6 +1,9 @@
 package jsonschema
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func NewError(expect, got any) *Error {
 	return &Error{
@@ -13,10 +16,29 @@ type Error struct {
 	expect any
 	got    any
 	name   string
+	causes []*Error
 }
 
 func (e *Error) Error() string {
-	return fmt.Sprintf("failed to validate %s; got: %v, expected: %v", e.name, e.got, e.expect)
+	res := fmt.Sprintf("failed to validate %s; got: %v, expected: %v", e.name, e.got, e.expect)
+	if len(e.causes) == 0 {
+		return res
+	}
+
+	causes := make([]string, 0, len(e.causes))
+	for i, cause := range e.causes {
+		if cause == nil {
+			continue
+		}
+
+		causes = append(causes, fmt.Sprintf("#%d: %s", i, cause.Error()))
+	}
+
+	if len(causes) == 0 {
+		return res
+	}
+
+	return res + " [" + strings.Join(causes, "; ") + "]"
 }
 
 func (e *Error) SetName(name string) *Error {
@@ -27,3 +49,15 @@ func (e *Error) SetName(name string) *Error {
 	e.name = name
 	return e
 }
+
+func (e *Error) SetCauses(causes []*Error) *Error {
+	e.causes = causes
+	return e
+}
+
+// Causes returns the errors of the subschemas that made e fail, indexed the
+// same way as the subschemas in the keyword. A nil entry means that subschema
+// passed.
+func (e *Error) Causes() []*Error {
+	return e.causes
+}
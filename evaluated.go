@@ -0,0 +1,138 @@
+package jsonschema
+
+// evaluated collects the annotations of one schema applied to one instance:
+// the object properties and array indices that the schema or any of its
+// passing in-place subschemas, like allOf or $ref, evaluated.
+// unevaluatedProperties and unevaluatedItems apply only to the rest.
+//
+// scope is the dynamic scope shared by the whole validation: the schema
+// resources entered so far, outermost first. $dynamicRef and $recursiveRef
+// are resolved against it.
+//
+// When collect is set, annotations holds the annotation keywords of the
+// passing schemas by instance location, the JSON Pointer in location.
+type evaluated struct {
+	properties  map[string]struct{}
+	items       map[int]struct{}
+	scope       *[]*Schema
+	collect     bool
+	location    string
+	annotations map[string][]Annotation
+}
+
+func newEvaluated() *evaluated {
+	return &evaluated{
+		scope: new([]*Schema),
+	}
+}
+
+func (e *evaluated) child() *evaluated {
+	return &evaluated{
+		scope:    e.scope,
+		collect:  e.collect,
+		location: e.location,
+	}
+}
+
+// validate validates target, which can be e's instance or a child of it,
+// against schema in the same dynamic scope.
+func (e *evaluated) validate(target any, schema *Schema) *Error {
+	_, err := e.evaluate(target, schema)
+	return err
+}
+
+// validateAt validates target, the member of e's instance named token,
+// against schema and keeps the annotations collected for it.
+func (e *evaluated) validateAt(token string, target any, schema *Schema) *Error {
+	if !e.collect {
+		return e.validate(target, schema)
+	}
+
+	child := e.child()
+	child.location += "/" + escapePointer(token)
+
+	res, err := child.evaluate(target, schema)
+	if err != nil {
+		return err
+	}
+
+	e.mergeAnnotations(res)
+
+	return nil
+}
+
+// enter adds the resource of schema to the dynamic scope unless it is
+// already the innermost one, and returns the func that removes it.
+func (e *evaluated) enter(schema *Schema) func() {
+	scope := *e.scope
+	if schema.resource == nil || (len(scope) > 0 && scope[len(scope)-1] == schema.resource) {
+		return func() {}
+	}
+
+	*e.scope = append(scope, schema.resource)
+
+	return func() {
+		*e.scope = (*e.scope)[:len(*e.scope)-1]
+	}
+}
+
+func (e *evaluated) addProperty(name string) {
+	if e.properties == nil {
+		e.properties = make(map[string]struct{})
+	}
+
+	e.properties[name] = struct{}{}
+}
+
+func (e *evaluated) addItem(i int) {
+	if e.items == nil {
+		e.items = make(map[int]struct{})
+	}
+
+	e.items[i] = struct{}{}
+}
+
+func (e *evaluated) merge(other *evaluated) {
+	if other == nil {
+		return
+	}
+
+	for name := range other.properties {
+		e.addProperty(name)
+	}
+
+	for i := range other.items {
+		e.addItem(i)
+	}
+
+	e.mergeAnnotations(other)
+}
+
+// annotate adds the annotation keywords of schema at e's location.
+func (e *evaluated) annotate(schema *Schema) {
+	if !e.collect || len(schema.annotations) == 0 {
+		return
+	}
+
+	if e.annotations == nil {
+		e.annotations = make(map[string][]Annotation)
+	}
+
+	for _, annotation := range schema.annotations {
+		e.annotations[e.location] = append(e.annotations[e.location], annotation)
+	}
+}
+
+func (e *evaluated) mergeAnnotations(other *evaluated) {
+	if len(other.annotations) == 0 {
+		return
+	}
+
+	if e.annotations == nil {
+		e.annotations = make(map[string][]Annotation)
+	}
+
+	for location, annotations := range other.annotations {
+		e.annotations[location] = append(e.annotations[location], annotations...)
+	}
+}
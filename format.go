@@ -0,0 +1,685 @@
+package jsonschema
+
+import (
+	"net"
+	"net/netip"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// checkFormat returns the validation of a format whose values valid accepts.
+func checkFormat(format any, valid func(string) bool) func(a any) *Error {
+	return func(a any) *Error {
+		if !valid(a.(string)) {
+			return NewError(format, a.(string))
+		}
+
+		return nil
+	}
+}
+
+// isDateTime follows the date-time rule of RFC 3339 section 5.6.
+func isDateTime(s string) bool {
+	if len(s) < 11 || (s[10] != 'T' && s[10] != 't') {
+		return false
+	}
+
+	return isDate(s[:10]) && isTime(s[11:])
+}
+
+// isDate follows the full-date rule of RFC 3339, checking the number of days
+// of the month in leap years too.
+func isDate(s string) bool {
+	if len(s) != 10 || s[4] != '-' || s[7] != '-' {
+		return false
+	}
+
+	year, ok := parseDigits(s[:4])
+	month, ok2 := parseDigits(s[5:7])
+	day, ok3 := parseDigits(s[8:])
+	if !ok || !ok2 || !ok3 || month < 1 || month > 12 || day < 1 {
+		return false
+	}
+
+	days := [...]int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}[month-1]
+	if month == 2 && year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+		days = 29
+	}
+
+	return day <= days
+}
+
+// isTime follows the full-time rule of RFC 3339, which requires an offset. A
+// leap second is only valid at 23:59:60 UTC.
+func isTime(s string) bool {
+	if len(s) < 9 || s[2] != ':' || s[5] != ':' {
+		return false
+	}
+
+	hour, ok := parseDigits(s[:2])
+	minute, ok2 := parseDigits(s[3:5])
+	second, ok3 := parseDigits(s[6:8])
+	if !ok || !ok2 || !ok3 || hour > 23 || minute > 59 || second > 60 {
+		return false
+	}
+
+	s = s[8:]
+
+	if s[0] == '.' {
+		n := leadingDigits(s[1:])
+		if n == 0 {
+			return false
+		}
+
+		s = s[n+1:]
+	}
+
+	var offset int
+
+	switch {
+	case s == "Z" || s == "z":
+	case len(s) == 6 && (s[0] == '+' || s[0] == '-') && s[3] == ':':
+		offsetHour, ok := parseDigits(s[1:3])
+		offsetMinute, ok2 := parseDigits(s[4:])
+		if !ok || !ok2 || offsetHour > 23 || offsetMinute > 59 {
+			return false
+		}
+
+		offset = offsetHour*60 + offsetMinute
+		if s[0] == '+' {
+			offset = -offset
+		}
+	default:
+		return false
+	}
+
+	if second == 60 {
+		utc := ((hour*60+minute+offset)%(24*60) + 24*60) % (24 * 60)
+		return utc == 23*60+59
+	}
+
+	return true
+}
+
+func parseDigits(s string) (int, bool) {
+	if s == "" || leadingDigits(s) != len(s) {
+		return 0, false
+	}
+
+	var res int
+	for i := 0; i < len(s); i++ {
+		res = res*10 + int(s[i]-'0')
+	}
+
+	return res, true
+}
+
+// durationRegexp follows the duration rule of RFC 3339 appendix A, where the
+// elements are in order without gaps and weeks don't mix with other units.
+var durationRegexp = regexp.MustCompile(`^P(?:[0-9]+W|(?:(?:[0-9]+Y(?:[0-9]+M(?:[0-9]+D)?)?|[0-9]+M(?:[0-9]+D)?|[0-9]+D)(?:` + durationTime + `)?|` + durationTime + `))$`)
+
+const durationTime = `T(?:[0-9]+H(?:[0-9]+M(?:[0-9]+S)?)?|[0-9]+M(?:[0-9]+S)?|[0-9]+S)`
+
+// isIPv4 follows the dotted-quad rule of RFC 2673: four decimal octets without
+// leading zeros.
+func isIPv4(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) != 4 {
+		return false
+	}
+
+	for _, part := range parts {
+		octet, ok := parseDigits(part)
+		if !ok || octet > 255 || (len(part) > 1 && part[0] == '0') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isIPv6 follows the text representation of RFC 4291 section 2.2, without a
+// zone.
+func isIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+
+	return err == nil && addr.Is6() && addr.Zone() == ""
+}
+
+// isJSONPointer follows RFC 6901: tokens start with / and ~ only starts the
+// escapes ~0 and ~1.
+func isJSONPointer(s string) bool {
+	if s != "" && s[0] != '/' {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '~' && (i+1 == len(s) || (s[i+1] != '0' && s[i+1] != '1')) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isRelativeJSONPointer follows draft-bhutton-relative-json-pointer-00: a
+// non-negative integer, an optional index manipulation, then # or a JSON
+// Pointer.
+func isRelativeJSONPointer(s string) bool {
+	n := leadingDigits(s)
+	if n == 0 || (s[0] == '0' && n > 1) {
+		return false
+	}
+
+	s = s[n:]
+
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		n = leadingDigits(s[1:])
+		if n == 0 || (s[1] == '0' && n > 1) {
+			return false
+		}
+
+		s = s[n+1:]
+	}
+
+	return s == "#" || isJSONPointer(s)
+}
+
+func leadingDigits(s string) int {
+	var n int
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+
+	return n
+}
+
+// isURIReference follows the URI-reference rule of RFC 3986, or the
+// IRI-reference rule of RFC 3987 when iri is set. absolute reports whether the
+// reference has a scheme.
+func isURIReference(s string, iri bool) (absolute bool, ok bool) {
+	ucschar, iprivate := isNotUnicode, isNotUnicode
+	if iri {
+		ucschar, iprivate = isUcschar, isIprivateOrUcschar
+	}
+
+	//a colon before any /, ? or # ends the scheme, a relative reference
+	//can't have one in its first segment
+	if i := strings.IndexAny(s, ":/?#"); i >= 0 && s[i] == ':' {
+		if !isScheme(s[:i]) {
+			return false, false
+		}
+
+		absolute = true
+		s = s[i+1:]
+	}
+
+	s, fragment, _ := strings.Cut(s, "#")
+	if !isURIPart(fragment, ":@/?", ucschar) {
+		return false, false
+	}
+
+	s, query, _ := strings.Cut(s, "?")
+	if !isURIPart(query, ":@/?", iprivate) {
+		return false, false
+	}
+
+	if rest, ok := strings.CutPrefix(s, "//"); ok {
+		authority, path, _ := strings.Cut(rest, "/")
+		if !isAuthority(authority, ucschar) {
+			return false, false
+		}
+
+		s = path
+	}
+
+	return absolute, isURIPart(s, ":@/", ucschar)
+}
+
+func isScheme(s string) bool {
+	if s == "" || !isAlpha(s[0]) {
+		return false
+	}
+
+	for i := 1; i < len(s); i++ {
+		if !isAlpha(s[i]) && !isDigit(s[i]) && !strings.ContainsRune("+-.", rune(s[i])) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isAuthority checks [ userinfo "@" ] host [ ":" port ], where host is an IP
+// literal in brackets or a registered name, which covers IPv4 addresses.
+func isAuthority(s string, ucschar func(rune) bool) bool {
+	if userinfo, host, ok := strings.Cut(s, "@"); ok {
+		if !isURIPart(userinfo, ":", ucschar) {
+			return false
+		}
+
+		s = host
+	}
+
+	host, port := s, ""
+
+	if strings.HasPrefix(s, "[") {
+		end := strings.Index(s, "]")
+		if end < 0 || !isIPLiteral(s[1:end]) {
+			return false
+		}
+
+		host, port = "", s[end+1:]
+		if port != "" {
+			if port[0] != ':' {
+				return false
+			}
+
+			port = port[1:]
+		}
+	} else if i := strings.LastIndex(s, ":"); i >= 0 {
+		host, port = s[:i], s[i+1:]
+	}
+
+	if leadingDigits(port) != len(port) {
+		return false
+	}
+
+	return isURIPart(host, "", ucschar)
+}
+
+// isIPLiteral checks the content of the brackets of an IP literal, which is
+// an IPv6 address or an IPvFuture.
+func isIPLiteral(s string) bool {
+	if s != "" && (s[0] == 'v' || s[0] == 'V') {
+		version, rest, ok := strings.Cut(s[1:], ".")
+		if !ok || version == "" || rest == "" {
+			return false
+		}
+
+		for i := 0; i < len(version); i++ {
+			if !isHex(version[i]) {
+				return false
+			}
+		}
+
+		return !strings.Contains(rest, "%") && isURIPart(rest, ":", isNotUnicode)
+	}
+
+	ip := net.ParseIP(s)
+
+	return ip != nil && strings.Contains(s, ":")
+}
+
+// isURIPart reports whether s consists of unreserved characters, sub-delims,
+// percent-encoded octets and the characters in extra. Characters outside of
+// ASCII are allowed when nonASCII accepts them.
+func isURIPart(s string, extra string, nonASCII func(rune) bool) bool {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case r == '%':
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				return false
+			}
+		case r >= utf8.RuneSelf:
+			if !nonASCII(r) {
+				return false
+			}
+		case !isUnreserved(s[i]) && !strings.ContainsRune("!$&'()*+,;="+extra, r):
+			return false
+		}
+
+		if r == '%' {
+			size = 3
+		}
+
+		i += size
+	}
+
+	return true
+}
+
+func isUnreserved(c byte) bool {
+	return isAlpha(c) || isDigit(c) || strings.IndexByte("-._~", c) >= 0
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isNotUnicode(r rune) bool {
+	return false
+}
+
+// isUcschar follows the ucschar rule of RFC 3987.
+func isUcschar(r rune) bool {
+	switch {
+	case r >= 0xA0 && r <= 0xD7FF, r >= 0xF900 && r <= 0xFDCF, r >= 0xFDF0 && r <= 0xFFEF:
+		return true
+	case r >= 0xE0000:
+		return r >= 0xE1000 && r <= 0xEFFFD
+	default:
+		return r >= 0x10000 && r&0xFFFF <= 0xFFFD
+	}
+}
+
+// isIprivateOrUcschar adds the private use characters that RFC 3987 allows
+// in queries.
+func isIprivateOrUcschar(r rune) bool {
+	switch {
+	case r >= 0xE000 && r <= 0xF8FF, r >= 0xF0000 && r <= 0xFFFFD, r >= 0x100000 && r <= 0x10FFFD:
+		return true
+	default:
+		return isUcschar(r)
+	}
+}
+
+// isURITemplate follows RFC 6570: literals with expressions in braces, each
+// an optional operator and comma separated variables with an optional prefix
+// or explode modifier.
+func isURITemplate(s string) bool {
+	for len(s) > 0 {
+		start := strings.IndexAny(s, "{}")
+		if start < 0 {
+			return isTemplateLiteral(s)
+		}
+
+		if s[start] == '}' || !isTemplateLiteral(s[:start]) {
+			return false
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 || !isTemplateExpression(s[start+1:start+end]) {
+			return false
+		}
+
+		s = s[start+end+1:]
+	}
+
+	return true
+}
+
+func isTemplateLiteral(s string) bool {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		switch {
+		case r == '%':
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				return false
+			}
+
+			size = 3
+		case r >= utf8.RuneSelf:
+			if !isIprivateOrUcschar(r) {
+				return false
+			}
+		case r <= ' ' || r == 0x7F || strings.ContainsRune("\"'<>\\^`{|}", r):
+			return false
+		}
+
+		i += size
+	}
+
+	return true
+}
+
+func isTemplateExpression(s string) bool {
+	if s != "" && strings.IndexByte("+#./;?&", s[0]) >= 0 {
+		s = s[1:]
+	}
+
+	for _, varspec := range strings.Split(s, ",") {
+		name, modifier := varspec, ""
+		if i := strings.IndexAny(varspec, ":*"); i >= 0 {
+			name, modifier = varspec[:i], varspec[i:]
+		}
+
+		if !isTemplateVarname(name) {
+			return false
+		}
+
+		switch {
+		case modifier == "", modifier == "*":
+		case modifier[0] == ':':
+			length := modifier[1:]
+			if length == "" || len(length) > 4 || length[0] == '0' || leadingDigits(length) != len(length) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func isTemplateVarname(s string) bool {
+	if s == "" || s[0] == '.' || s[len(s)-1] == '.' || strings.Contains(s, "..") {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '%':
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				return false
+			}
+
+			i += 2
+		case !isAlpha(s[i]) && !isDigit(s[i]) && s[i] != '_' && s[i] != '.':
+			return false
+		}
+	}
+
+	return true
+}
+
+// isHostname follows RFC 1123: dot separated labels of letters, digits and
+// hyphens, at most 63 characters each and 253 in total, that don't start or
+// end with a hyphen. A trailing dot for the root is allowed and labels
+// starting with xn-- must be valid A-labels.
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for i := 0; i < len(label); i++ {
+			if !isAlpha(label[i]) && !isDigit(label[i]) && label[i] != '-' {
+				return false
+			}
+		}
+
+		//labels with hyphens in the 3rd and 4th position are reserved,
+		//only the A-labels of RFC 5891 are in use
+		if len(label) >= 4 && label[2:4] == "--" {
+			if !strings.EqualFold(label[:2], "xn") {
+				return false
+			}
+
+			if _, err := idna.Registration.ToUnicode(strings.ToLower(label)); err != nil {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// isIDNHostname follows RFC 5890 and RFC 5891: every label is a valid
+// U-label or A-label, and the contextual rules of RFC 5892 hold. ASCII
+// letters are compared case insensitively.
+func isIDNHostname(s string) bool {
+	s = strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf {
+			return unicode.ToLower(r)
+		}
+
+		return r
+	}, s)
+
+	ascii, err := idna.Registration.ToASCII(strings.TrimSuffix(s, "."))
+	if err != nil || !isHostname(ascii) {
+		return false
+	}
+
+	unicodeName, err := idna.Registration.ToUnicode(ascii)
+	if err != nil {
+		return false
+	}
+
+	for _, label := range strings.Split(unicodeName, ".") {
+		if !isContextO(label) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isContextO checks the CONTEXTO rules of RFC 5892 appendix A and the
+// exceptions that UTS #46, which idna implements, still allows.
+func isContextO(label string) bool {
+	runes := []rune(label)
+
+	var arabicIndic, extendedArabicIndic, japanese bool
+	for _, r := range runes {
+		switch {
+		case r >= 0x0660 && r <= 0x0669:
+			arabicIndic = true
+		case r >= 0x06F0 && r <= 0x06F9:
+			extendedArabicIndic = true
+		case r != 0x30FB && unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han):
+			japanese = true
+		}
+	}
+
+	if arabicIndic && extendedArabicIndic {
+		return false
+	}
+
+	for i, r := range runes {
+		switch r {
+		//exceptions that are DISALLOWED by RFC 5892 section 2.6
+		case 0x0640, 0x07FA, 0x302E, 0x302F, 0x3031, 0x3032, 0x3033, 0x3034, 0x3035, 0x303B:
+			return false
+		//MIDDLE DOT
+		case 0x00B7:
+			if i == 0 || i == len(runes)-1 || runes[i-1] != 'l' || runes[i+1] != 'l' {
+				return false
+			}
+		//GREEK LOWER NUMERAL SIGN
+		case 0x0375:
+			if i == len(runes)-1 || !unicode.Is(unicode.Greek, runes[i+1]) {
+				return false
+			}
+		//HEBREW PUNCTUATION GERESH and GERSHAYIM
+		case 0x05F3, 0x05F4:
+			if i == 0 || !unicode.Is(unicode.Hebrew, runes[i-1]) {
+				return false
+			}
+		//KATAKANA MIDDLE DOT
+		case 0x30FB:
+			if !japanese {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// isEmail follows the Mailbox rule of RFC 5321, or RFC 6531 when idn is set,
+// which allows UTF-8 in the local part and an IDN domain.
+func isEmail(s string, idn bool) bool {
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		return false
+	}
+
+	local, domain := s[:at], s[at+1:]
+
+	if strings.HasPrefix(domain, "[") && strings.HasSuffix(domain, "]") {
+		literal := domain[1 : len(domain)-1]
+
+		if ipv6, ok := strings.CutPrefix(literal, "IPv6:"); ok {
+			return strings.Contains(ipv6, ":") && net.ParseIP(ipv6) != nil && isLocalPart(local, idn)
+		}
+
+		ip := net.ParseIP(literal)
+
+		return ip != nil && ip.To4() != nil && !strings.Contains(literal, ":") && isLocalPart(local, idn)
+	}
+
+	if idn {
+		return isIDNHostname(domain) && isLocalPart(local, idn)
+	}
+
+	return isHostname(domain) && isLocalPart(local, idn)
+}
+
+// isLocalPart checks a dot separated string of atoms or a quoted string.
+func isLocalPart(s string, idn bool) bool {
+	if s == "" || (!idn && len(s) > 64) {
+		return false
+	}
+
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		for i := 1; i < len(s)-1; i++ {
+			switch c := s[i]; {
+			case c == '\\':
+				i++
+				if i == len(s)-1 || s[i] < 32 || s[i] > 126 {
+					return false
+				}
+			case c >= utf8.RuneSelf:
+				if !idn {
+					return false
+				}
+			case c < 32 || c > 126 || c == '"':
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, atom := range strings.Split(s, ".") {
+		if atom == "" {
+			return false
+		}
+
+		for _, r := range atom {
+			switch {
+			case r >= utf8.RuneSelf:
+				if !idn {
+					return false
+				}
+			case !isAlpha(byte(r)) && !isDigit(byte(r)) && !strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r):
+				return false
+			}
+		}
+	}
+
+	return true
+}
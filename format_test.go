@@ -0,0 +1,338 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+// formatTest is a case of the optional format tests of the JSON Schema Test
+// Suite.
+type formatTest struct {
+	format   string
+	instance string
+	valid    bool
+}
+
+func testFormats(t *testing.T, tests []formatTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.instance, func(t *testing.T) {
+			err := NewValidator().Validate(jsonString(tt.instance), `{"format": "`+tt.format+`"}`)
+			if (err == nil) != tt.valid {
+				t.Errorf("valid = %v, want %v: %v", err == nil, tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testFormats(t, []formatTest{
+		{"date-time", "1963-06-19T08:30:06.283185Z", true},
+		{"date-time", "1963-06-19T08:30:06Z", true},
+		{"date-time", "1937-01-01T12:00:27.87+00:20", true},
+		{"date-time", "1990-12-31T15:59:50.123-08:00", true},
+		{"date-time", "1998-12-31T23:59:60Z", true},
+		{"date-time", "1998-12-31T15:59:60.123-08:00", true},
+		{"date-time", "1998-12-31T23:59:61Z", false},
+		{"date-time", "1998-12-31T23:58:60Z", false},
+		{"date-time", "1998-12-31T22:59:60Z", false},
+		{"date-time", "1990-02-31T15:59:59.123-08:00", false},
+		{"date-time", "1990-12-31T15:59:59-24:00", false},
+		{"date-time", "1963-06-19T08:30:06.28123+01:00Z", false},
+		{"date-time", "06/19/1963 08:30:06 PST", false},
+		{"date-time", "1963-06-19t08:30:06.283185z", true},
+		{"date-time", "2013-350T01:01:01", false},
+		{"date-time", "1963-6-19T08:30:06.283185Z", false},
+		{"date-time", "1963-06-1T08:30:06.283185Z", false},
+		{"date-time", "1963-06-1\u09EAT00:00:00Z", false},
+		{"date-time", "1963-06-11T0\u09EA:00:00Z", false},
+
+		{"time", "08:30:06Z", true},
+		{"time", "23:59:60Z", true},
+		{"time", "23:59:60+00:00", true},
+		{"time", "22:59:60+00:00", false},
+		{"time", "01:29:60+01:30", true},
+		{"time", "23:29:60+23:30", true},
+		{"time", "23:59:60+01:00", false},
+		{"time", "23:59:60+00:30", false},
+		{"time", "15:59:60-08:00", true},
+		{"time", "00:29:60-23:30", true},
+		{"time", "23:59:60-01:00", false},
+		{"time", "23:59:60-00:30", false},
+		{"time", "23:20:50.52Z", true},
+		{"time", "08:30:06.283185Z", true},
+		{"time", "08:30:06+00:20", true},
+		{"time", "08:30:06-08:00", true},
+		{"time", "08:30:06z", true},
+		{"time", "24:00:00Z", false},
+		{"time", "00:60:00Z", false},
+		{"time", "00:00:61Z", false},
+		{"time", "22:59:60Z", false},
+		{"time", "23:58:60Z", false},
+		{"time", "01:02:03+24:00", false},
+		{"time", "01:02:03+00:60", false},
+		{"time", "01:02:03Z+00:30", false},
+		{"time", "08:30:06 PST", false},
+		{"time", "01:01:01,1111", false},
+		{"time", "12:00:00", false},
+		{"time", "12:00:00.52", false},
+		{"time", "1\u09E8:00:00Z", false},
+		{"time", "08:30:06#00:20", false},
+		{"time", "ab:cd:ef", false},
+
+		{"duration", "P4DT12H30M5S", true},
+		{"duration", "PT1D", false},
+		{"duration", "P", false},
+		{"duration", "P1YT", false},
+		{"duration", "PT", false},
+		{"duration", "P2D1Y", false},
+		{"duration", "P1D2H", false},
+		{"duration", "P2S", false},
+		{"duration", "P4Y", true},
+		{"duration", "PT0S", true},
+		{"duration", "P0D", true},
+		{"duration", "P1M", true},
+		{"duration", "PT1M", true},
+		{"duration", "PT36H", true},
+		{"duration", "P1W", true},
+		{"duration", "P1Y2W", false},
+		{"duration", "P\u09E8Y", false},
+		{"duration", "1h", false},
+
+		{"hostname", "www.example.com", true},
+		{"hostname", "xn--4gbwdl.xn--wgbh1c", true},
+		{"hostname", "-a-host-name-that-starts-with--", false},
+		{"hostname", "not_a_valid_host_name", false},
+		{"hostname", "a-vvvvvvvvvvvvvvvveeeeeeeeeeeeeeeerrrrrrrrrrrrrrrryyyyyyyyyyyyyyyy-long-host-name-component", false},
+		{"hostname", "-hostname", false},
+		{"hostname", "hostname-", false},
+		{"hostname", "_hostname", false},
+		{"hostname", "hostname_", false},
+		{"hostname", "host_name", false},
+		{"hostname", "hostname", true},
+		{"hostname", "h0stn4me", true},
+		{"hostname", "1host", true},
+		{"hostname", "hostnam3", true},
+		{"hostname", "", false},
+		{"hostname", ".", false},
+		{"hostname", "XN--aa---o47jg78q", false},
+
+		{"email", "joe.bloggs@example.com", true},
+		{"email", "2962", false},
+		{"email", "te~st@example.com", true},
+		{"email", "~test@example.com", true},
+		{"email", "test~@example.com", true},
+		{"email", `"joe bloggs"@example.com`, true},
+		{"email", `"joe..bloggs"@example.com`, true},
+		{"email", `"joe@bloggs"@example.com`, true},
+		{"email", "joe.bloggs@[127.0.0.1]", true},
+		{"email", "joe.bloggs@[IPv6:::1]", true},
+		{"email", ".test@example.com", false},
+		{"email", "test.@example.com", false},
+		{"email", "te.s.t@example.com", true},
+		{"email", "te..st@example.com", false},
+		{"email", "joe.bloggs@invalid=domain.com", false},
+		{"email", "joe.bloggs@[127.0.0.300]", false},
+
+		{"ipv4", "192.168.0.1", true},
+		{"ipv4", "127.0.0.0.1", false},
+		{"ipv4", "256.256.256.256", false},
+		{"ipv4", "127.0", false},
+		{"ipv4", "0x7f000001", false},
+		{"ipv4", "2130706433", false},
+		{"ipv4", "1\u09E87.0.0.1", false},
+		{"ipv4", "087.10.0.1", false},
+		{"ipv4", "87.10.0.1", true},
+		{"ipv4", "192.168.1.0/24", false},
+		{"ipv4", "::1", false},
+
+		{"ipv6", "::1", true},
+		{"ipv6", "12345::", false},
+		{"ipv6", "::abef", true},
+		{"ipv6", "1:1:1:1:1:1:1:1:1:1:1:1:1:1:1:1", false},
+		{"ipv6", "::laptop", false},
+		{"ipv6", "::", true},
+		{"ipv6", ":2:3:4:5:6:7:8", false},
+		{"ipv6", "1:2:3:4:5:6:7:", false},
+		{"ipv6", "1:d6::42", true},
+		{"ipv6", "1::d6::42", false},
+		{"ipv6", "1::d6:192.168.0.1", true},
+		{"ipv6", "1:2::192.168.256.1", false},
+		{"ipv6", "1:2::192.168.ff.1", false},
+		{"ipv6", "::ffff:192.168.0.1", true},
+		{"ipv6", " ::1", false},
+		{"ipv6", "::1 ", false},
+		{"ipv6", "fe80::/64", false},
+		{"ipv6", "fe80::a%eth1", false},
+		{"ipv6", "1:2:3:4:5:6:7:8", true},
+		{"ipv6", "1:2:3:4:5:6:7:8:9", false},
+		{"ipv6", "::42:ff:1", true},
+		{"ipv6", "100:100:100:100:100:100:255.255.255.255", true},
+		{"ipv6", "1:2:3:4:5:::8", false},
+		{"ipv6", "::1:2:3:4:5:6:7", true},
+		{"ipv6", "0000:0000:0000:0000:0000:0000:0000:0000", true},
+		{"ipv6", "192.168.0.1", false},
+		{"ipv6", "::\u09E7", false},
+
+		{"uri", "http://foo.bar/?baz=qux#quux", true},
+		{"uri", "http://foo.com/blah_(wikipedia)_blah#cite-1", true},
+		{"uri", "http://foo.bar/?q=Test%20URL-encoded%20stuff", true},
+		{"uri", "http://xn--nw2a.xn--j6w193g/", true},
+		{"uri", "http://-.~_!$&'()*+,;=:%40:80%2f::::::@example.com", true},
+		{"uri", "http://223.255.255.254", true},
+		{"uri", "ftp://ftp.is.co.za/rfc/rfc1808.txt", true},
+		{"uri", "http://www.ietf.org/rfc/rfc2396.txt", true},
+		{"uri", "ldap://[2001:db8::7]/c=GB?objectClass?one", true},
+		{"uri", "mailto:John.Doe@example.com", true},
+		{"uri", "news:comp.infosystems.www.servers.unix", true},
+		{"uri", "tel:+1-816-555-1212", true},
+		{"uri", "urn:oasis:names:specification:docbook:dtd:xml:4.1.2", true},
+		{"uri", "//foo.bar/?baz=qux#quux", false},
+		{"uri", "/abc", false},
+		{"uri", `\\WINDOWS\fileshare`, false},
+		{"uri", "abc", false},
+		{"uri", "http:// shouldfail.com", false},
+		{"uri", ":// should fail", false},
+		{"uri", "bar,baz:foo", false},
+		{"uri", "https://[@example.org/test.txt", false},
+		{"uri", "https://example.org/foobar\\.txt", false},
+		{"uri", "http://example.com/\u00E9", false},
+	})
+}
+
+func TestFormatVocabulary(t *testing.T) {
+	testFormats(t, []formatTest{
+		{"idn-email", "\uC2E4\uB840@\uC2E4\uB840.\uD14C\uC2A4\uD2B8", true},
+		{"idn-email", "2962", false},
+		{"idn-email", "joe.bloggs@example.com", true},
+
+		{"idn-hostname", "\uC2E4\uB840.\uD14C\uC2A4\uD2B8", true},
+		{"idn-hostname", "\u302E\uC2E4\uB840.\uD14C\uC2A4\uD2B8", false},
+		{"idn-hostname", "\uC2E4\u302E\uB840.\uD14C\uC2A4\uD2B8", false},
+		{"idn-hostname", strings.Repeat("\uC2E4", 64) + ".\uD14C\uC2A4\uD2B8", false},
+		{"idn-hostname", "-> $1.00 <--", false},
+		{"idn-hostname", "xn--ihqwcrb4cv8a8dqg056pqjye", true},
+		{"idn-hostname", "xn--X", false},
+		{"idn-hostname", "XN--aa---o47jg78q", false},
+		{"idn-hostname", "-hello", false},
+		{"idn-hostname", "hello-", false},
+		{"idn-hostname", "-hello-", false},
+		{"idn-hostname", "\u0903hello", false},
+		{"idn-hostname", "\u0300hello", false},
+		{"idn-hostname", "\u0488hello", false},
+		{"idn-hostname", "\u00DF\u03C2\u0F0B\u3007", true},
+		{"idn-hostname", "\u06FD\u06FE", true},
+		{"idn-hostname", "\u0640\u07FA", false},
+		{"idn-hostname", "\u3031\u3032\u3033\u3034\u3035\u302E\u302F\u303B", false},
+		{"idn-hostname", "a\u00B7l", false},
+		{"idn-hostname", "\u00B7l", false},
+		{"idn-hostname", "l\u00B7a", false},
+		{"idn-hostname", "l\u00B7", false},
+		{"idn-hostname", "l\u00B7l", true},
+		{"idn-hostname", "\u03B1\u0375S", false},
+		{"idn-hostname", "\u03B1\u0375", false},
+		{"idn-hostname", "\u03B1\u0375\u03B2", true},
+		{"idn-hostname", "A\u05F3\u05D1", false},
+		{"idn-hostname", "\u05F3\u05D1", false},
+		{"idn-hostname", "\u05D0\u05F3\u05D1", true},
+		{"idn-hostname", "A\u05F4\u05D1", false},
+		{"idn-hostname", "\u05F4\u05D1", false},
+		{"idn-hostname", "\u05D0\u05F4\u05D1", true},
+		{"idn-hostname", "def\u30FBabc", false},
+		{"idn-hostname", "\u30FB", false},
+		{"idn-hostname", "\u30FB\u3041", true},
+		{"idn-hostname", "\u30FB\u30A1", true},
+		{"idn-hostname", "\u30FB\u4E08", true},
+		{"idn-hostname", "\u0628\u0660\u06F0", false},
+		{"idn-hostname", "\u0628\u0660\u0628", true},
+		{"idn-hostname", "\u06F00", true},
+		{"idn-hostname", "\u0915\u200D\u0937", false},
+		{"idn-hostname", "\u200D\u0937", false},
+		{"idn-hostname", "\u0915\u094D\u200D\u0937", true},
+		{"idn-hostname", "\u0915\u200C\u0937", false},
+		{"idn-hostname", "\u200C\u0937", false},
+		{"idn-hostname", "\u0915\u094D\u200C\u0937", true},
+		{"idn-hostname", "\u0628\u064A\u200C\u0628\u064A", true},
+		{"idn-hostname", "hello", true},
+
+		{"iri", "http://\u0192\u00F8\u00F8.\u00DF\u00E5r/?\u2202\u00E9\u0153=\u03C0\u00EEx#\u03C0\u00EE\u00FCx", true},
+		{"iri", "http://\u0192\u00F8\u00F8.com/blah_(w\u00EEk\u00EFp\u00E9di\u00E5)_blah#\u0109i\u01631", true},
+		{"iri", "http://\u0192\u00F8\u00F8.\u00DF\u00E5r/?q=Test%20URL-encoded%20stuff", true},
+		{"iri", "http://-.~_!$&'()*+,;=:%40:80%2f::::::@example.com", true},
+		{"iri", "http://[2001:0db8:85a3:0000:0000:8a2e:0370:7334]", true},
+		{"iri", "http://[2001:0db8:85a3:0000:0000:8a2e:0370]", false},
+		{"iri", "/abc", false},
+		{"iri", "\\\\WINDOWS\\fil\u00EB\u00DF\u00E5r\u00E9", false},
+		{"iri", "\u00E2\u03C0\u03C0", false},
+		{"iri", "http://2001:0db8:85a3:0000:0000:8a2e:0370:7334", false},
+
+		{"uri-reference", "http://foo.bar/?baz=qux#quux", true},
+		{"uri-reference", "//foo.bar/?baz=qux#quux", true},
+		{"uri-reference", "/abc", true},
+		{"uri-reference", `\\WINDOWS\fileshare`, false},
+		{"uri-reference", "abc", true},
+		{"uri-reference", "#fragment", true},
+		{"uri-reference", `#frag\ment`, false},
+
+		{"iri-reference", "http://\u0192\u00F8\u00F8.\u00DF\u00E5r/?\u2202\u00E9\u0153=\u03C0\u00EEx#\u03C0\u00EE\u00FCx", true},
+		{"iri-reference", "//\u0192\u00F8\u00F8.\u00DF\u00E5r/?\u2202\u00E9\u0153=\u03C0\u00EEx#\u03C0\u00EE\u00FCx", true},
+		{"iri-reference", "/\u00E2\u03C0\u03C0", true},
+		{"iri-reference", "\\\\WINDOWS\\fil\u00EB\u00DF\u00E5r\u00E9", false},
+		{"iri-reference", "\u00E2\u03C0\u03C0", true},
+		{"iri-reference", "#\u0192r\u00E4gm\u00EAnt", true},
+		{"iri-reference", "#\u0192r\u00E4g\\m\u00EAnt", false},
+
+		{"uri-template", "http://example.com/dictionary/{term:1}/{term}", true},
+		{"uri-template", "http://example.com/dictionary/{term:1}/{term", false},
+		{"uri-template", "http://example.com/dictionary", true},
+		{"uri-template", "dictionary/{term:1}/{term}", true},
+
+		{"json-pointer", "/foo/bar~0/baz~1/%a", true},
+		{"json-pointer", "/foo/bar~", false},
+		{"json-pointer", "/foo//bar", true},
+		{"json-pointer", "/foo/bar/", true},
+		{"json-pointer", "", true},
+		{"json-pointer", "/foo", true},
+		{"json-pointer", "/foo/0", true},
+		{"json-pointer", "/", true},
+		{"json-pointer", "/a~1b", true},
+		{"json-pointer", "/c%d", true},
+		{"json-pointer", "/e^f", true},
+		{"json-pointer", "/g|h", true},
+		{"json-pointer", `/i\j`, true},
+		{"json-pointer", `/k"l`, true},
+		{"json-pointer", "/ ", true},
+		{"json-pointer", "/m~0n", true},
+		{"json-pointer", "/foo/-", true},
+		{"json-pointer", "/foo/-/bar", true},
+		{"json-pointer", "/~1~0~0~1~1", true},
+		{"json-pointer", "/~1.1", true},
+		{"json-pointer", "/~0~", false},
+		{"json-pointer", "/~0~2", false},
+		{"json-pointer", "/~-1", false},
+		{"json-pointer", "/~~", false},
+		{"json-pointer", "#", false},
+		{"json-pointer", "#/", false},
+		{"json-pointer", "#a", false},
+		{"json-pointer", "a", false},
+		{"json-pointer", "0", false},
+		{"json-pointer", "a/a", false},
+
+		{"relative-json-pointer", "1", true},
+		{"relative-json-pointer", "0/foo/bar", true},
+		{"relative-json-pointer", "2/0/baz/1/zip", true},
+		{"relative-json-pointer", "0#", true},
+		{"relative-json-pointer", "/foo/bar", false},
+		{"relative-json-pointer", "-1/foo/bar", false},
+		{"relative-json-pointer", "+1/foo/bar", false},
+		{"relative-json-pointer", "0##", false},
+		{"relative-json-pointer", "01/a", false},
+		{"relative-json-pointer", "01#", false},
+		{"relative-json-pointer", "", false},
+		{"relative-json-pointer", "120/foo/bar", true},
+		{"relative-json-pointer", "0+1/foo", true},
+		{"relative-json-pointer", "0-1/foo", true},
+		{"relative-json-pointer", "0+01/foo", false},
+	})
+}
@@ -37,5 +37,5 @@ require (
 
 require (
 	github.com/google/uuid v1.6.0
-	golang.org/x/net v0.25.0 // indirect
+	golang.org/x/net v0.25.0
 )
@@ -0,0 +1,78 @@
+package jsonschema
+
+import "testing"
+
+func TestGraphemeCount(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want int
+	}{
+		{"empty", "", 0},
+		{"ASCII", "abc", 3},
+		{"CRLF", "\r\n", 1},
+		{"LFCR", "\n\r", 2},
+		{"CRLF between letters", "a\r\nb", 3},
+		{"control before a mark", "\t\u0301", 2},
+		{"combining mark", "e\u0301", 1},
+		{"combining marks", "e\u0301\u0323", 1},
+		{"leading combining mark", "\u0301a", 2},
+		{"emoji modifier", "\U0001F44D\U0001F3FD", 1},
+		{"ZWJ sequence", "\U0001F468\u200D\U0001F469\u200D\U0001F467", 1},
+		{"ZWJ sequence with modifier", "\U0001F469\U0001F3FD\u200D\U0001F4BB", 1},
+		{"ZWJ after a letter", "a\u200D\U0001F469", 2},
+		{"flag", "\U0001F1FA\U0001F1F8", 1},
+		{"two flags", "\U0001F1FA\U0001F1F8\U0001F1EB\U0001F1F7", 2},
+		{"odd regional indicators", "\U0001F1FA\U0001F1F8\U0001F1EB", 2},
+		{"lone regional indicator", "\U0001F1FA", 1},
+		{"Hangul syllables", "\uD55C\uAE00", 2},
+		{"Hangul L V T", "\u1112\u1161\u11AB", 1},
+		{"Hangul L L V", "\u1100\u1100\u1161", 1},
+		{"Hangul LV T", "\uAC00\u11A8", 1},
+		{"Hangul LVT V", "\uAC01\u1161", 2},
+		{"Hangul T L", "\u11A8\u1100", 2},
+		{"prepend", "\u0600\u0661", 1},
+		{"prepend before a control", "\u0600\n", 2},
+		{"spacing mark", "\u0915\u093F", 1},
+		{"Thai sara am", "\u0E01\u0E33", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := graphemeCount(tt.s); got != tt.want {
+				t.Errorf("graphemeCount(%+q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphemeLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		graphemes bool
+		schema    string
+		valid     bool
+	}{
+		{"code points over maxLength", false, `{"maxLength": 1}`, false},
+		{"code points within maxLength", false, `{"maxLength": 2, "minLength": 2}`, true},
+		{"graphemes within maxLength", true, `{"maxLength": 1}`, true},
+		{"graphemes under minLength", true, `{"minLength": 2}`, false},
+	}
+
+	//a flag is one grapheme cluster of two code points
+	instance := `"\ud83c\uddfa\ud83c\uddf8"`
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []Option
+			if tt.graphemes {
+				options = append(options, WithGraphemeLength())
+			}
+
+			err := NewValidator(options...).Validate(instance, tt.schema)
+			if (err == nil) != tt.valid {
+				t.Errorf("valid = %v, want %v: %v", err == nil, tt.valid, err)
+			}
+		})
+	}
+}
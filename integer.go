@@ -2,22 +2,40 @@ package jsonschema
 
 import "errors"
 
-var integerValidation map[string]rawValidation = map[string]rawValidation{
-	"minimum": {
-		function: minimumInteger,
-	},
-	"exclusiveMinimum": {
-		function: exclusiveMinimumInteger,
-	},
-	"maximum": {
-		function: maximumInteger,
-	},
-	"exclusiveMaximum": {
-		function: exclusiveMaximumInteger,
-	},
-	"multipleOf": {
-		function: multipleOfInteger,
-	},
+func integerValidationFor(version int) map[string]rawValidation {
+	if version < 6 {
+		return map[string]rawValidation{
+			"minimum": {
+				function: draft4Bound("exclusiveMinimum", minimumInteger, exclusiveMinimumInteger),
+				optional: []string{"exclusiveMinimum"},
+			},
+			"maximum": {
+				function: draft4Bound("exclusiveMaximum", maximumInteger, exclusiveMaximumInteger),
+				optional: []string{"exclusiveMaximum"},
+			},
+			"multipleOf": {
+				function: multipleOfInteger,
+			},
+		}
+	}
+
+	return map[string]rawValidation{
+		"minimum": {
+			function: minimumInteger,
+		},
+		"exclusiveMinimum": {
+			function: exclusiveMinimumInteger,
+		},
+		"maximum": {
+			function: maximumInteger,
+		},
+		"exclusiveMaximum": {
+			function: exclusiveMaximumInteger,
+		},
+		"multipleOf": {
+			function: multipleOfInteger,
+		},
+	}
 }
 
 func multipleOfInteger(value any) (func(a any) *Error, error) {
@@ -26,13 +44,12 @@ func multipleOfInteger(value any) (func(a any) *Error, error) {
 		return nil, errors.New("multipleOf requires integer")
 	}
 
-	if float64(int(v)) != v {
+	if !isInteger(v) {
 		return nil, errors.New("multipleOf requires integer")
 	}
 
 	return func(a any) *Error {
-		check := float64(a.(int)) / v
-		if float64(int(check)) != check {
+		if !isInteger(a.(float64) / v) {
 			return NewError(v, a)
 		}
 
@@ -46,16 +63,16 @@ func exclusiveMaximumInteger(max any) (func(a any) *Error, error) {
 		return nil, errors.New("exclusiveMaximum requires integer")
 	}
 
-	if float64(int(v)) != v {
+	if !isInteger(v) {
 		return nil, errors.New("exclusiveMaximum requires integer")
 	}
 
 	return func(a any) *Error {
-		if v > float64(a.(int)) {
+		if v > a.(float64) {
 			return nil
 		}
 
-		return NewError(float64(a.(int)), v)
+		return NewError(a.(float64), v)
 	}, nil
 }
 
@@ -65,16 +82,16 @@ func maximumInteger(max any) (func(a any) *Error, error) {
 		return nil, errors.New("maximum requires integer")
 	}
 
-	if float64(int(v)) != v {
+	if !isInteger(v) {
 		return nil, errors.New("maximum requires integer")
 	}
 
 	return func(a any) *Error {
-		if v >= float64(a.(int)) {
+		if v >= a.(float64) {
 			return nil
 		}
 
-		return NewError(float64(a.(int)), v)
+		return NewError(a.(float64), v)
 	}, nil
 }
 
@@ -84,16 +101,16 @@ func exclusiveMinimumInteger(min any) (func(a any) *Error, error) {
 		return nil, errors.New("minimum requires integer")
 	}
 
-	if float64(int(v)) != v {
+	if !isInteger(v) {
 		return nil, errors.New("minimum requires integer")
 	}
 
 	return func(a any) *Error {
-		if v < float64(a.(int)) {
+		if v < a.(float64) {
 			return nil
 		}
 
-		return NewError(float64(a.(int)), v)
+		return NewError(a.(float64), v)
 	}, nil
 }
 
@@ -103,15 +120,15 @@ func minimumInteger(min any) (func(a any) *Error, error) {
 		return nil, errors.New("minimum requires integer")
 	}
 
-	if float64(int(v)) != v {
+	if !isInteger(v) {
 		return nil, errors.New("minimum requires integer")
 	}
 
 	return func(a any) *Error {
-		if v <= float64(a.(int)) {
+		if v <= a.(float64) {
 			return nil
 		}
 
-		return NewError(float64(a.(int)), v)
+		return NewError(a.(float64), v)
 	}, nil
 }
@@ -3,9 +3,12 @@ package jsonschema
 import (
 	"encoding/json"
 	"errors"
+	"math"
 	"net/url"
 	"os"
 	"reflect"
+	"slices"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -32,90 +35,178 @@ func validateValueType(value ValueType) bool {
 }
 
 type Schema struct {
-	valueType    ValueType
-	validateFunc map[string]validateFunc
-	properties   map[string]Schema
+	falseSchema  bool
+	valueTypes   []ValueType
+	validateFunc map[ValueType]map[string]validateFunc
+	commonFunc   map[string]validateFunc
+	condition    *condition
+	ref          *Schema
+	dynamicRef   *dynamicRef
+	finalFunc    map[string]validateFunc
+	annotations  []Annotation
+
+	//inPlace holds the subschemas other than $ref that apply to the same
+	//instance, like those of allOf, to find cycles at compile time
+	inPlace []*Schema
+
+	//resource is the root of the schema resource the schema belongs to, the
+	//fields below are set on resource roots only
+	resource        *Schema
+	dynamicAnchors  map[string]*Schema
+	recursiveAnchor bool
 }
 
-type validateFunc func(any) *Error
+// validateFunc checks one keyword and records the properties and items it
+// evaluated in the given evaluated.
+type validateFunc func(any, *evaluated) *Error
 type rawValidateFunc func(any) (func(any) *Error, error)
+type rawCompileFunc func(*compiler, any) (validateFunc, error)
 
+// Validate validates target against schema with the default settings of
+// NewValidator.
 func Validate(target any, schema any) error {
-	validatedTarget, err := validateTarget(reflect.ValueOf(target))
-	if err != nil {
-		return err
+	return NewValidator().Validate(target, schema)
+}
+
+func validate(target any, schema *Schema) *Error {
+	return newEvaluated().validate(target, schema)
+}
+
+// evaluate validates target against schema and returns what the schema
+// evaluated in it. Keywords in finalFunc run last to see everything the
+// other keywords evaluated.
+func (e *evaluated) evaluate(target any, schema *Schema) (*evaluated, *Error) {
+	var res *evaluated = e.child()
+
+	if schema.falseSchema {
+		return nil, NewError(false, target).SetName("schema")
+	}
+
+	defer e.enter(schema)()
+
+	if schema.ref != nil {
+		ref, err := res.evaluate(target, schema.ref)
+		if err != nil {
+			return nil, err.SetName("$ref")
+		}
+
+		res.merge(ref)
 	}
 
-	validatedSchema, err := validateSchema(reflect.ValueOf(schema))
+	if schema.dynamicRef != nil {
+		ref, err := res.evaluate(target, schema.dynamicRef.resolve(*e.scope))
+		if err != nil {
+			return nil, err.SetName("$dynamicRef")
+		}
+
+		res.merge(ref)
+	}
+
+	valueType, err := checkType(target, schema)
 	if err != nil {
-		return err
+		return nil, err.SetName("type")
 	}
 
-	err = validate(validatedTarget, validatedSchema)
-	if err != (*Error)(nil) {
-		return err
+	err = validateByType(target, valueType, schema, res)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	err = validateValue(target, schema.commonFunc, res)
+	if err != nil {
+		return nil, err
+	}
 
-func validate(target any, schema *Schema) *Error {
-	switch schema.valueType {
-	case String:
-		if _, ok := target.(string); !ok {
-			return NewError("string", reflect.ValueOf(target).Kind().String())
+	if schema.condition != nil {
+		err = schema.condition.validate(target, res)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		return validateValue(target.(string), schema)
-	case Integer:
-		if _, ok := target.(float64); !ok {
-			return NewError("int", reflect.ValueOf(target).Kind().String())
-		}
+	err = validateValue(target, schema.finalFunc, res)
+	if err != nil {
+		return nil, err
+	}
 
-		v := target.(float64)
+	res.annotate(schema)
 
-		if float64(int(v)) != v {
-			return NewError("int", "float64")
-		}
+	return res, nil
+}
 
-		return validateValue(int(v), schema)
-	case Number:
-		if _, ok := target.(float64); !ok {
-			return NewError("float64", reflect.ValueOf(target).Kind().String())
-		}
+// checkType returns the type of target and checks it against the types the
+// schema allows. An integer is a number too, so it matches both.
+func checkType(target any, schema *Schema) (ValueType, *Error) {
+	valueType, ok := typeOf(target)
+	if !ok {
+		return "", NewError(schema.valueTypes, reflect.ValueOf(target).Kind().String())
+	}
 
-		return validateValue(target.(float64), schema)
-	case Boolean:
-		if _, ok := target.(bool); !ok {
-			return NewError("bool", reflect.ValueOf(target).Kind().String())
-		}
+	if len(schema.valueTypes) == 0 {
+		return valueType, nil
+	}
 
-		return validateValue(target.(bool), schema)
-	case Null:
-		if target != nil {
-			return NewError("null", reflect.ValueOf(target).Kind().String())
+	for _, allowed := range schema.valueTypes {
+		if allowed == valueType || (allowed == Number && valueType == Integer) {
+			return valueType, nil
 		}
+	}
 
-		return nil
-	case Array:
-		if _, ok := target.([]any); !ok {
-			return NewError("slice", reflect.ValueOf(target).Kind().String())
+	if len(schema.valueTypes) == 1 {
+		return "", NewError(schema.valueTypes[0], valueType)
+	}
+
+	return "", NewError(schema.valueTypes, valueType)
+}
+
+func typeOf(target any) (ValueType, bool) {
+	switch v := target.(type) {
+	case nil:
+		return Null, true
+	case bool:
+		return Boolean, true
+	case string:
+		return String, true
+	case float64:
+		if isInteger(v) {
+			return Integer, true
 		}
 
-		return validateValue(target.([]any), schema)
-	case Object:
-		if _, ok := target.(map[string]any); !ok {
-			return NewError("object", reflect.ValueOf(target).Kind().String())
+		return Number, true
+	case []any:
+		return Array, true
+	case map[string]any:
+		return Object, true
+	default:
+		return "", false
+	}
+}
+
+// isInteger reports whether v has no fractional part. Integers past the range
+// of int, like 1e20, are integers too.
+func isInteger(v float64) bool {
+	return math.Trunc(v) == v && !math.IsInf(v, 0)
+}
+
+// validateByType runs the keywords compiled for the type of target. Integers
+// use the integer keywords when the schema has them and the number keywords
+// otherwise.
+func validateByType(target any, valueType ValueType, schema *Schema, e *evaluated) *Error {
+	switch valueType {
+	case Integer:
+		if funcs, ok := schema.validateFunc[Integer]; ok {
+			return validateValue(target, funcs, e)
 		}
 
-		return validateValue(target, schema)
+		return validateValue(target, schema.validateFunc[Number], e)
+	default:
+		return validateValue(target, schema.validateFunc[valueType], e)
 	}
-	return nil
 }
 
-func validateValue(target any, schema *Schema) *Error {
-	for name, value := range schema.validateFunc {
-		err := value(target)
+func validateValue(target any, funcs map[string]validateFunc, e *evaluated) *Error {
+	for name, value := range funcs {
+		err := value(target, e)
 		if err != nil {
 			return err.SetName(name)
 		}
@@ -124,11 +215,11 @@ func validateValue(target any, schema *Schema) *Error {
 	return nil
 }
 
-func validateSchema(value reflect.Value) (*Schema, error) {
+func validateSchema(v *Validator, value reflect.Value) (*Schema, error) {
 	switch value.Kind() {
 	//json/path/url
 	case reflect.String:
-		return schemaFromString(value.String())
+		return schemaFromString(v, value.String())
 	//json bytes
 	case reflect.Slice:
 		bytes, ok := value.Interface().([]byte)
@@ -136,7 +227,7 @@ func validateSchema(value reflect.Value) (*Schema, error) {
 			return nil, errors.New("unknown target, supports only slice of bytes")
 		}
 
-		return schemaFromString(string(bytes))
+		return schemaFromString(v, string(bytes))
 	case reflect.Map:
 		schema, ok := value.Interface().(Schema)
 		if !ok {
@@ -144,10 +235,20 @@ func validateSchema(value reflect.Value) (*Schema, error) {
 		}
 
 		return &schema, nil
+	//boolean schema
+	case reflect.Bool:
+		c := newCompiler(v)
+
+		err := c.addDocument("", value.Bool())
+		if err != nil {
+			return nil, err
+		}
+
+		return c.compileDocument("")
 	case reflect.Pointer:
 		schema, ok := value.Interface().(*Schema)
 		if !ok {
-			return validateSchema(value.Elem())
+			return validateSchema(v, value.Elem())
 		}
 
 		return schema, nil
@@ -156,76 +257,157 @@ func validateSchema(value reflect.Value) (*Schema, error) {
 	}
 }
 
-func schemaFromString(str string) (*Schema, error) {
+func schemaFromString(v *Validator, str string) (*Schema, error) {
 	data, err := JSONFromString(str)
 	if err != nil {
 		return nil, err
 	}
 
-	var values map[string]interface{} = make(map[string]interface{})
+	var values any
 
 	err = json.Unmarshal([]byte(data), &values)
 	if err != nil {
 		return nil, err
 	}
 
-	return createSchemaFromJSON(values)
+	c := newCompiler(v)
+	uri := documentURI(str)
+
+	err = c.addDocument(uri, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.compileDocument(uri)
 }
 
-func createSchemaFromJSON(values map[string]interface{}) (*Schema, error) {
-	var res *Schema = &Schema{}
+func fillSchemaFromJSON(c *compiler, res *Schema, values map[string]interface{}) error {
+	if raw, ok := values["$ref"]; ok {
+		ref, ok := raw.(string)
+		if !ok {
+			return errors.New("$ref requires string")
+		}
 
-	valueType, ok := getValueType(values["type"])
-	if !ok {
-		return nil, errors.New("schema has wrong type")
-	}
+		schema, err := c.resolve(ref)
+		if err != nil {
+			return err
+		}
 
-	res.valueType = valueType
+		res.ref = schema
 
-	validation, err := getValidation(valueType, values)
-	if err != nil {
-		return nil, err
+		//before 2019-09 keywords next to $ref are ignored
+		if c.dialect.version < 2019 {
+			return nil
+		}
 	}
 
-	res.validateFunc = validation
-
-	if valueType == Object {
-		raw, ok := values["properties"]
-		//can be empty - skip
+	if raw, ok := values["$dynamicRef"]; ok && c.dialect.version >= 2020 {
+		ref, ok := raw.(string)
 		if !ok {
-			return res, nil
+			return errors.New("$dynamicRef requires string")
+		}
+
+		dynamicRef, err := c.dynamicRef(ref)
+		if err != nil {
+			return err
 		}
 
-		properties, ok := raw.(map[string]interface{})
+		res.dynamicRef = dynamicRef
+	}
+
+	if raw, ok := values["$recursiveRef"]; ok && c.dialect.version == 2019 {
+		ref, ok := raw.(string)
 		if !ok {
-			return nil, errors.New("schema has wrong properties")
+			return errors.New("$recursiveRef requires string")
 		}
 
-		for key, value := range properties {
-			prop, ok := value.(map[string]interface{})
-			if !ok {
-				continue
-			}
+		dynamicRef, err := c.recursiveRef(ref)
+		if err != nil {
+			return err
+		}
 
-			newSchema, err := createSchemaFromJSON(prop)
-			if err != nil {
-				return nil, err
-			}
+		res.dynamicRef = dynamicRef
+	}
 
-			if res.properties == nil {
-				res.properties = make(map[string]Schema)
-			}
+	if c.dialect.version == 2019 {
+		res.recursiveAnchor, _ = values["$recursiveAnchor"].(bool)
+	}
+
+	res.annotations = getAnnotations(c.dialect, values)
+
+	common, err := getValidation(c, c.dialect.common, values)
+	if err != nil {
+		return err
+	}
+
+	res.commonFunc = common
+
+	//if, then and else exist since draft-07
+	if c.dialect.version >= 7 && c.dialect.allows("if") {
+		res.condition, err = getCondition(c, values)
+		if err != nil {
+			return err
+		}
+	}
+
+	res.finalFunc, err = getValidation(c, c.dialect.final, values)
+	if err != nil {
+		return err
+	}
+
+	var typeValue any
+	if c.dialect.allows("type") {
+		typeValue = values["type"]
+	}
+
+	valueTypes, ok := getValueTypes(typeValue)
+	if !ok {
+		return errors.New("schema has wrong type")
+	}
+
+	res.valueTypes = valueTypes
+
+	res.validateFunc, err = getTypedValidation(c, valueTypes, values)
+	if err != nil {
+		return err
+	}
+
+	err = getCustomKeywords(c, res, values)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getTypedValidation compiles the type specific keywords of values for every
+// type in valueTypes. A schema without type compiles them for every type, so
+// {"minLength": 3} checks strings and lets other values pass.
+func getTypedValidation(c *compiler, valueTypes []ValueType, values map[string]interface{}) (map[ValueType]map[string]validateFunc, error) {
+	if len(valueTypes) == 0 {
+		valueTypes = []ValueType{String, Number, Object, Array, Boolean, Null}
+	}
+
+	var res map[ValueType]map[string]validateFunc = make(map[ValueType]map[string]validateFunc)
+
+	for _, valueType := range valueTypes {
+		validation, err := getValidation(c, c.dialect.validations[valueType], values)
+		if err != nil {
+			return nil, err
+		}
 
-			res.properties[key] = *newSchema
+		if validation == nil {
+			validation = make(map[string]validateFunc)
 		}
+
+		res[valueType] = validation
 	}
 
 	return res, nil
 }
 
-func getValidation(valueType ValueType, values map[string]interface{}) (map[string]validateFunc, error) {
-	validation, ok := validations[valueType]
-	if !ok {
+func getValidation(c *compiler, validation map[string]rawValidation, values map[string]interface{}) (map[string]validateFunc, error) {
+	if validation == nil {
 		return nil, nil
 	}
 
@@ -237,15 +419,15 @@ func getValidation(valueType ValueType, values map[string]interface{}) (map[stri
 			continue
 		}
 
-		if len(validation.requires) == 0 {
-			validate, err := validation.function(value)
-			if err != nil {
-				return nil, err
-			}
+		//custom keywords replace built-in ones
+		if _, ok := c.customKeyword(name); ok {
+			continue
+		}
 
-			res[name] = validate
-		} else {
-			var input []any = []any{value}
+		var input any = value
+
+		if len(validation.requires) != 0 || len(validation.optional) != 0 {
+			var list []any = []any{value}
 
 			for _, requires := range validation.requires {
 				value, ok = values[requires]
@@ -253,33 +435,84 @@ func getValidation(valueType ValueType, values map[string]interface{}) (map[stri
 					return nil, errors.New(name + " requires " + requires)
 				}
 
-				input = append(input, value)
+				list = append(list, value)
 			}
 
-			validate, err := validation.function(input)
+			//missing optional keywords are passed as nil
+			for _, optional := range validation.optional {
+				list = append(list, values[optional])
+			}
+
+			input = list
+		}
+
+		if validation.compile != nil {
+			c.location = append(c.location, name)
+			validate, err := validation.compile(c, input)
+			c.location = c.location[:len(c.location)-1]
 			if err != nil {
 				return nil, err
 			}
 
 			res[name] = validate
-		}
+		} else {
+			validate, err := validation.function(input)
+			if err != nil {
+				return nil, err
+			}
 
+			res[name] = func(a any, _ *evaluated) *Error {
+				return validate(a)
+			}
+		}
 	}
 
 	return res, nil
 }
 
+// rawValidation describes how to compile one keyword. Keywords with subschemas
+// use compile instead of function to reach the compiler. Keywords listed in
+// requires and optional are passed after the keyword value in one slice.
 type rawValidation struct {
 	function rawValidateFunc
+	compile  rawCompileFunc
 	requires []string
+	optional []string
 }
 
-var validations map[ValueType]map[string]rawValidation = map[ValueType]map[string]rawValidation{
-	String:  stringValidation,
-	Integer: integerValidation,
-	Number:  numberValidation,
-	Array:   sliceValidation,
-	Object:  objectValidation,
+// getValueTypes reads type, which is either one type name or an array of
+// unique type names. A missing type allows any type and returns nil.
+func getValueTypes(value any) ([]ValueType, bool) {
+	if value == nil {
+		return nil, true
+	}
+
+	if _, ok := value.(string); ok {
+		valueType, ok := getValueType(value)
+		if !ok {
+			return nil, false
+		}
+
+		return []ValueType{valueType}, true
+	}
+
+	values, ok := value.([]any)
+	if !ok {
+		return nil, false
+	}
+
+	res := make([]ValueType, 0, len(values))
+
+	for _, value := range values {
+		valueType, ok := getValueType(value)
+		if !ok || slices.Contains(res, valueType) {
+			return nil, false
+		}
+
+		res = append(res, valueType)
+	}
+
+	return res, true
 }
 
 func getValueType(value any) (ValueType, bool) {
@@ -346,7 +579,7 @@ func targetFromString(str string) (any, error) {
 }
 
 func JSONFromString(str string) (string, error) {
-	if _, err := url.ParseRequestURI(str); err == nil {
+	if u, err := url.ParseRequestURI(str); err == nil && u.Scheme != "" {
 		return getJSONFromUrl(str)
 	}
 
@@ -360,8 +593,12 @@ func JSONFromString(str string) (string, error) {
 	return str, nil
 }
 
+// fetchTimeout bounds the time spent fetching a schema, so unreachable hosts
+// fail the $ref instead of hanging the compilation.
+const fetchTimeout = 10 * time.Second
+
 func getJSONFromUrl(url string) (string, error) {
-	response, err := resty.New().R().EnableTrace().Get(url)
+	response, err := resty.New().SetTimeout(fetchTimeout).R().EnableTrace().Get(url)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,228 @@
+package jsonschema
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Keyword is a custom schema keyword, like x-maxDecimalPlaces. Compile runs
+// once for every schema that has the keyword and returns what validates
+// instances against it.
+type Keyword interface {
+	Compile(c *CompileContext, value any) (KeywordValidator, error)
+}
+
+// KeywordValidator validates an instance against a compiled keyword. The
+// instance is decoded JSON, numbers are float64. An error that is an *Error
+// is returned as is, any other error is reported with its message as the
+// expected value. A panic is returned as an error too.
+type KeywordValidator interface {
+	Validate(c *ValidateContext, instance any) error
+}
+
+// KeywordFunc is a KeywordValidator made of a function.
+type KeywordFunc func(c *ValidateContext, instance any) error
+
+func (f KeywordFunc) Validate(c *ValidateContext, instance any) error {
+	return f(c, instance)
+}
+
+// CompileContext gives a custom keyword access to the schema it is in.
+type CompileContext struct {
+	c      *compiler
+	values map[string]any
+}
+
+// Compile compiles a subschema found in the keyword value that applies to the
+// same instance as the keyword, like the schemas of anyOf. tokens is the path
+// to it inside the value, like "0" for the first schema of an array, and is
+// used to resolve $ref and $id in the subschema. Schemas whose subschemas
+// lead back to themselves this way, like {"x-also": {"$ref": "#"}}, fail to
+// compile.
+func (c *CompileContext) Compile(value any, tokens ...string) (*Schema, error) {
+	return c.c.compileInPlace(value, tokens...)
+}
+
+// CompileChild compiles a subschema found in the keyword value that applies to
+// the items or properties of the instance, like the schema of items. Unlike
+// Compile, it can refer back to the schema of the keyword.
+func (c *CompileContext) CompileChild(value any, tokens ...string) (*Schema, error) {
+	return c.c.compile(value, tokens...)
+}
+
+// Value returns the value of another keyword of the same schema.
+func (c *CompileContext) Value(keyword string) (any, bool) {
+	value, ok := c.values[keyword]
+	return value, ok
+}
+
+// ValidateContext gives a custom keyword access to the running validation.
+type ValidateContext struct {
+	e *evaluated
+}
+
+// Validate validates instance against a schema compiled with
+// CompileContext.Compile or CompileContext.CompileChild.
+func (c *ValidateContext) Validate(instance any, schema *Schema) error {
+	err := c.e.validate(instance, schema)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// customKeyword is a registered keyword and the types it applies to, nil
+// for all types.
+type customKeyword struct {
+	keyword    Keyword
+	valueTypes []ValueType
+}
+
+var (
+	keywordsMu sync.RWMutex
+	keywords   = make(map[string]customKeyword)
+)
+
+// RegisterKeyword adds a keyword for every validator. It applies to
+// instances of valueTypes, or of any type when none are given; Number covers
+// integers too. Keywords added with WithKeyword take precedence. A keyword
+// with the name of a built-in keyword, like minLength, replaces it for all
+// types, the core keywords, like $ref, type or if, can't be replaced and
+// schemas using them fail to compile.
+func RegisterKeyword(name string, keyword Keyword, valueTypes ...ValueType) {
+	keywordsMu.Lock()
+	defer keywordsMu.Unlock()
+
+	keywords[name] = customKeyword{
+		keyword:    keyword,
+		valueTypes: valueTypes,
+	}
+}
+
+// coreKeywords are the keywords the compiler handles itself rather than
+// through the keyword tables of the dialect.
+var coreKeywords = []string{
+	"$schema", "$id", "id", "$ref", "$anchor", "$dynamicRef", "$dynamicAnchor",
+	"$recursiveRef", "$recursiveAnchor", "$vocabulary", "$defs", "definitions",
+	"$comment", "type", "if", "then", "else",
+}
+
+// customKeyword returns the custom keyword named name of the validator or the
+// registered one.
+func (c *compiler) customKeyword(name string) (customKeyword, bool) {
+	if keyword, ok := c.validator.keywords[name]; ok {
+		return keyword, true
+	}
+
+	keywordsMu.RLock()
+	defer keywordsMu.RUnlock()
+
+	keyword, ok := keywords[name]
+	return keyword, ok
+}
+
+// getCustomKeywords compiles the custom keywords of values into res, into the
+// common keywords or the keywords of the types they apply to. getValidation
+// skips the built-in keywords they replace.
+func getCustomKeywords(c *compiler, res *Schema, values map[string]any) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		keyword, ok := c.customKeyword(name)
+		if !ok {
+			continue
+		}
+
+		if slices.Contains(coreKeywords, name) {
+			return errors.New("custom keyword " + name + " can't replace a core keyword")
+		}
+
+		validate, err := compileKeyword(c, name, keyword.keyword, values[name], values)
+		if err != nil {
+			return err
+		}
+
+		if len(keyword.valueTypes) == 0 {
+			if res.commonFunc == nil {
+				res.commonFunc = make(map[string]validateFunc)
+			}
+
+			res.commonFunc[name] = validate
+			continue
+		}
+
+		for valueType, funcs := range res.validateFunc {
+			switch {
+			case slices.Contains(keyword.valueTypes, valueType):
+			case valueType == Integer && slices.Contains(keyword.valueTypes, Number):
+			//typeless schemas check integers with the number keywords
+			case valueType == Number && slices.Contains(keyword.valueTypes, Integer):
+				funcs[name] = onlyIntegers(validate)
+				continue
+			default:
+				continue
+			}
+
+			funcs[name] = validate
+		}
+	}
+
+	return nil
+}
+
+func compileKeyword(c *compiler, name string, keyword Keyword, value any, values map[string]any) (validate validateFunc, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panicked: %v", name, r)
+		}
+	}()
+
+	c.location = append(c.location, name)
+	defer func() {
+		c.location = c.location[:len(c.location)-1]
+	}()
+
+	validator, err := keyword.Compile(&CompileContext{c: c, values: values}, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(a any, e *evaluated) (res *Error) {
+		defer func() {
+			if r := recover(); r != nil {
+				res = NewError("no panic", fmt.Sprintf("panic: %v", r))
+			}
+		}()
+
+		failed := validator.Validate(&ValidateContext{e: e}, a)
+		if failed == nil {
+			return nil
+		}
+
+		var schemaErr *Error
+		if errors.As(failed, &schemaErr) {
+			return schemaErr
+		}
+
+		return NewError(failed.Error(), a)
+	}, nil
+}
+
+func onlyIntegers(validate validateFunc) validateFunc {
+	return func(a any, e *evaluated) *Error {
+		if valueType, _ := typeOf(a); valueType != Integer {
+			return nil
+		}
+
+		return validate(a, e)
+	}
+}
@@ -0,0 +1,322 @@
+package jsonschema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// compileFunc is a Keyword made of a function.
+type compileFunc func(c *CompileContext, value any) (KeywordValidator, error)
+
+func (f compileFunc) Compile(c *CompileContext, value any) (KeywordValidator, error) {
+	return f(c, value)
+}
+
+// maxDecimalPlaces is x-maxDecimalPlaces, the number of digits a number may
+// have after the point.
+var maxDecimalPlaces = compileFunc(func(_ *CompileContext, value any) (KeywordValidator, error) {
+	places, ok := value.(float64)
+	if !ok {
+		return nil, errors.New("x-maxDecimalPlaces requires number")
+	}
+
+	return KeywordFunc(func(_ *ValidateContext, instance any) error {
+		s := fmt.Sprint(instance.(float64))
+		_, decimals, _ := strings.Cut(s, ".")
+		if len(decimals) > int(places) {
+			return fmt.Errorf("at most %v decimal places", places)
+		}
+
+		return nil
+	}), nil
+})
+
+// lengthIs checks the length of strings, to replace minLength and maxLength.
+var lengthIs = compileFunc(func(_ *CompileContext, value any) (KeywordValidator, error) {
+	return KeywordFunc(func(_ *ValidateContext, instance any) error {
+		s, ok := instance.(string)
+		if ok && float64(len(s)) != value.(float64) {
+			return errors.New("exact length")
+		}
+
+		return nil
+	}), nil
+})
+
+// even fails for odd numbers, to check which instances a keyword sees.
+var even = compileFunc(func(_ *CompileContext, _ any) (KeywordValidator, error) {
+	return KeywordFunc(func(_ *ValidateContext, instance any) error {
+		if int(instance.(float64))%2 != 0 {
+			return errors.New("even")
+		}
+
+		return nil
+	}), nil
+})
+
+func reject(message string) Keyword {
+	return compileFunc(func(_ *CompileContext, _ any) (KeywordValidator, error) {
+		return KeywordFunc(func(_ *ValidateContext, _ any) error {
+			return errors.New(message)
+		}), nil
+	})
+}
+
+// nonEmptyOf is x-nonEmptyOf: an array of schemas like anyOf, where the
+// instance also has to match the schema of x-minimum when it is set.
+var nonEmptyOf = compileFunc(func(c *CompileContext, value any) (KeywordValidator, error) {
+	values, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("x-nonEmptyOf requires array")
+	}
+
+	schemas := make([]*Schema, 0, len(values))
+	for i, value := range values {
+		schema, err := c.Compile(value, fmt.Sprint(i))
+		if err != nil {
+			return nil, err
+		}
+
+		schemas = append(schemas, schema)
+	}
+
+	var minimum *Schema
+	if raw, ok := c.Value("x-minimum"); ok {
+		schema, err := c.Compile(map[string]any{"minimum": raw})
+		if err != nil {
+			return nil, err
+		}
+
+		minimum = schema
+	}
+
+	return KeywordFunc(func(c *ValidateContext, instance any) error {
+		if minimum != nil {
+			if err := c.Validate(instance, minimum); err != nil {
+				return err
+			}
+		}
+
+		for _, schema := range schemas {
+			if c.Validate(instance, schema) == nil {
+				return nil
+			}
+		}
+
+		return errors.New("one of x-nonEmptyOf")
+	}), nil
+})
+
+// eachItem is x-eachItem, a schema every item of an array has to match.
+var eachItem = compileFunc(func(c *CompileContext, value any) (KeywordValidator, error) {
+	schema, err := c.CompileChild(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return KeywordFunc(func(c *ValidateContext, instance any) error {
+		for _, item := range instance.([]any) {
+			if err := c.Validate(item, schema); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}), nil
+})
+
+func TestKeyword(t *testing.T) {
+	RegisterKeyword("x-registered", reject("registered"))
+	RegisterKeyword("x-shadowed", reject("registered"))
+	t.Cleanup(func() {
+		keywordsMu.Lock()
+		defer keywordsMu.Unlock()
+
+		delete(keywords, "x-registered")
+		delete(keywords, "x-shadowed")
+	})
+
+	tests := []struct {
+		name     string
+		options  []Option
+		schema   string
+		instance string
+		//err is a part of the expected error, empty for valid instances
+		err string
+	}{
+		{
+			name:     "valid",
+			options:  []Option{WithKeyword("x-maxDecimalPlaces", maxDecimalPlaces, Number)},
+			schema:   `{"x-maxDecimalPlaces": 2}`,
+			instance: `1.25`,
+		},
+		{
+			name:     "plain errors are reported with their message",
+			options:  []Option{WithKeyword("x-maxDecimalPlaces", maxDecimalPlaces, Number)},
+			schema:   `{"x-maxDecimalPlaces": 2}`,
+			instance: `1.255`,
+			err:      "failed to validate x-maxDecimalPlaces; got: 1.255, expected: at most 2 decimal places",
+		},
+		{
+			name:     "other types are not checked",
+			options:  []Option{WithKeyword("x-maxDecimalPlaces", maxDecimalPlaces, Number)},
+			schema:   `{"x-maxDecimalPlaces": 2}`,
+			instance: `"1.255"`,
+		},
+		{
+			name:     "Number keywords get integers as float64",
+			options:  []Option{WithKeyword("x-maxDecimalPlaces", maxDecimalPlaces, Number)},
+			schema:   `{"type": "integer", "x-maxDecimalPlaces": 0}`,
+			instance: `4`,
+		},
+		{
+			name:     "compile errors",
+			options:  []Option{WithKeyword("x-maxDecimalPlaces", maxDecimalPlaces, Number)},
+			schema:   `{"x-maxDecimalPlaces": "2"}`,
+			instance: `1`,
+			err:      "x-maxDecimalPlaces requires number",
+		},
+		{
+			name:     "Integer keywords check integers",
+			options:  []Option{WithKeyword("x-even", even, Integer)},
+			schema:   `{"x-even": true}`,
+			instance: `3`,
+			err:      "expected: even",
+		},
+		{
+			name:     "Integer keywords skip other numbers",
+			options:  []Option{WithKeyword("x-even", even, Integer)},
+			schema:   `{"x-even": true}`,
+			instance: `3.5`,
+		},
+		{
+			name:     "Integer keywords under type number skip other numbers",
+			options:  []Option{WithKeyword("x-even", even, Integer)},
+			schema:   `{"type": "number", "x-even": true}`,
+			instance: `3.5`,
+		},
+		{
+			name:     "registered keyword",
+			schema:   `{"x-registered": true}`,
+			instance: `1`,
+			err:      "expected: registered",
+		},
+		{
+			name:     "WithKeyword takes precedence over RegisterKeyword",
+			options:  []Option{WithKeyword("x-shadowed", reject("validator"))},
+			schema:   `{"x-shadowed": true}`,
+			instance: `1`,
+			err:      "expected: validator",
+		},
+		{
+			name: "panic in Compile",
+			options: []Option{WithKeyword("x-panic", compileFunc(func(*CompileContext, any) (KeywordValidator, error) {
+				panic("boom")
+			}))},
+			schema:   `{"x-panic": true}`,
+			instance: `1`,
+			err:      "x-panic panicked: boom",
+		},
+		{
+			name: "panic in Validate",
+			options: []Option{WithKeyword("x-panic", compileFunc(func(*CompileContext, any) (KeywordValidator, error) {
+				return KeywordFunc(func(*ValidateContext, any) error {
+					panic("boom")
+				}), nil
+			}))},
+			schema:   `{"x-panic": true}`,
+			instance: `1`,
+			err:      "got: panic: boom",
+		},
+		{
+			name:     "subschemas",
+			options:  []Option{WithKeyword("x-nonEmptyOf", nonEmptyOf)},
+			schema:   `{"x-nonEmptyOf": [{"type": "string"}, {"type": "integer"}], "x-minimum": 3}`,
+			instance: `4`,
+		},
+		{
+			name:     "subschemas failing",
+			options:  []Option{WithKeyword("x-nonEmptyOf", nonEmptyOf)},
+			schema:   `{"x-nonEmptyOf": [{"type": "string"}, {"type": "integer"}], "x-minimum": 3}`,
+			instance: `4.5`,
+			err:      "expected: one of x-nonEmptyOf",
+		},
+		{
+			name:     "subschema errors are returned as is",
+			options:  []Option{WithKeyword("x-nonEmptyOf", nonEmptyOf)},
+			schema:   `{"x-nonEmptyOf": [{"type": "integer"}], "x-minimum": 3}`,
+			instance: `2`,
+			err:      "failed to validate minimum; got: 2, expected: 3",
+		},
+		{
+			name:     "subschemas with $ref",
+			options:  []Option{WithKeyword("x-nonEmptyOf", nonEmptyOf)},
+			schema:   `{"$defs": {"s": {"type": "string"}}, "properties": {"a": {"x-nonEmptyOf": [{"$ref": "#/$defs/s"}]}}}`,
+			instance: `{"a": 1}`,
+			err:      "expected: one of x-nonEmptyOf",
+		},
+		{
+			name:     "subschemas referring to their own schema",
+			options:  []Option{WithKeyword("x-nonEmptyOf", nonEmptyOf)},
+			schema:   `{"x-nonEmptyOf": [{"$ref": "#"}]}`,
+			instance: `1`,
+			err:      "schema cycle at",
+		},
+		{
+			name:     "child subschemas referring to their own schema",
+			options:  []Option{WithKeyword("x-eachItem", eachItem, Array)},
+			schema:   `{"type": ["array", "integer"], "x-eachItem": {"$ref": "#"}}`,
+			instance: `[1, [2, [3]]]`,
+		},
+		{
+			name:     "child subschemas referring to their own schema failing",
+			options:  []Option{WithKeyword("x-eachItem", eachItem, Array)},
+			schema:   `{"type": ["array", "integer"], "x-eachItem": {"$ref": "#"}}`,
+			instance: `[1, [2, ["3"]]]`,
+			err:      "expected: [array integer]",
+		},
+		{
+			name:     "typed keyword replaces a built-in one",
+			options:  []Option{WithKeyword("minLength", lengthIs, String)},
+			schema:   `{"minLength": 3}`,
+			instance: `"abcd"`,
+			err:      "expected: exact length",
+		},
+		{
+			name:     "untyped keyword replaces a built-in one",
+			options:  []Option{WithKeyword("maxLength", lengthIs)},
+			schema:   `{"maxLength": 3}`,
+			instance: `"ab"`,
+			err:      "expected: exact length",
+		},
+		{
+			name:     "replaced keywords don't compile the built-in one",
+			options:  []Option{WithKeyword("maxLength", lengthIs)},
+			schema:   `{"maxLength": 3.5}`,
+			instance: `"ab"`,
+			err:      "expected: exact length",
+		},
+		{
+			name:     "core keywords can't be replaced",
+			options:  []Option{WithKeyword("type", lengthIs)},
+			schema:   `{"type": "string"}`,
+			instance: `"ab"`,
+			err:      "custom keyword type can't replace a core keyword",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(tt.options...).Validate(tt.instance, tt.schema)
+
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("got %v, want valid", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -4,22 +4,66 @@ import (
 	"errors"
 )
 
-var numberValidation map[string]rawValidation = map[string]rawValidation{
-	"minimum": {
-		function: minimum,
-	},
-	"exclusiveMinimum": {
-		function: exclusiveMinimum,
-	},
-	"maximum": {
-		function: maximum,
-	},
-	"exclusiveMaximum": {
-		function: exclusiveMaximum,
-	},
-	"multipleOf": {
-		function: multipleOf,
-	},
+// numberValidationFor returns the number keywords of a dialect. Before
+// draft-06 exclusiveMinimum and exclusiveMaximum are booleans that make
+// minimum and maximum exclusive.
+func numberValidationFor(version int) map[string]rawValidation {
+	if version < 6 {
+		return map[string]rawValidation{
+			"minimum": {
+				function: draft4Bound("exclusiveMinimum", minimum, exclusiveMinimum),
+				optional: []string{"exclusiveMinimum"},
+			},
+			"maximum": {
+				function: draft4Bound("exclusiveMaximum", maximum, exclusiveMaximum),
+				optional: []string{"exclusiveMaximum"},
+			},
+			"multipleOf": {
+				function: multipleOf,
+			},
+		}
+	}
+
+	return map[string]rawValidation{
+		"minimum": {
+			function: minimum,
+		},
+		"exclusiveMinimum": {
+			function: exclusiveMinimum,
+		},
+		"maximum": {
+			function: maximum,
+		},
+		"exclusiveMaximum": {
+			function: exclusiveMaximum,
+		},
+		"multipleOf": {
+			function: multipleOf,
+		},
+	}
+}
+
+// draft4Bound compiles a draft-04 minimum or maximum, which is checked by
+// exclusive instead of inclusive when the sibling named exclusive is true.
+func draft4Bound(exclusive string, inclusiveFunc, exclusiveFunc rawValidateFunc) rawValidateFunc {
+	return func(value any) (func(a any) *Error, error) {
+		v := value.([]any)
+
+		if v[1] == nil {
+			return inclusiveFunc(v[0])
+		}
+
+		isExclusive, ok := v[1].(bool)
+		if !ok {
+			return nil, errors.New(exclusive + " requires boolean")
+		}
+
+		if isExclusive {
+			return exclusiveFunc(v[0])
+		}
+
+		return inclusiveFunc(v[0])
+	}
 }
 
 func multipleOf(value any) (func(a any) *Error, error) {
@@ -29,8 +73,7 @@ func multipleOf(value any) (func(a any) *Error, error) {
 	}
 
 	return func(a any) *Error {
-		check := a.(float64) / v
-		if float64(int(check)) == check {
+		if isInteger(a.(float64) / v) {
 			return nil
 		}
 
@@ -0,0 +1,72 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLargeInteger(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		instance string
+		//err is a part of the expected error, empty for valid instances
+		err string
+	}{
+		{
+			name:     "integer type",
+			schema:   `{"type": "integer"}`,
+			instance: `1e20`,
+		},
+		{
+			name:     "integer type with a fraction",
+			schema:   `{"type": "integer"}`,
+			instance: `1.5`,
+			err:      "expected: integer",
+		},
+		{
+			name:     "integer type past the range of float64 precision",
+			schema:   `{"type": "integer"}`,
+			instance: `-9007199254740993000`,
+		},
+		{
+			name:     "maximum",
+			schema:   `{"type": "integer", "maximum": 5}`,
+			instance: `1e20`,
+			err:      "failed to validate maximum",
+		},
+		{
+			name:     "minimum",
+			schema:   `{"type": "integer", "minimum": 1e19}`,
+			instance: `1e20`,
+		},
+		{
+			name:     "integer multipleOf",
+			schema:   `{"type": "integer", "multipleOf": 3}`,
+			instance: `3e20`,
+		},
+		{
+			name:     "number multipleOf",
+			schema:   `{"multipleOf": 0.5}`,
+			instance: `1e20`,
+		},
+		{
+			name:     "enum",
+			schema:   `{"enum": [100000000000000000000]}`,
+			instance: `1e20`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator().Validate(tt.instance, tt.schema)
+
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("got %v, want valid", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -2,65 +2,110 @@ package jsonschema
 
 import (
 	"errors"
-	"regexp"
+	"sort"
 )
 
-var objectValidation map[string]rawValidation = map[string]rawValidation{
-	"properties": {
-		function: properties,
-	},
-	"required": {
-		function: required,
-	},
-	"dependentRequired": {
-		function: dependentRequired,
-	},
-	"minProperties": {
-		function: minProperties,
-	},
-	"maxProperties": {
-		function: maxProperties,
-	},
-	"propertyNames": {
-		function: propertyNames,
-	},
-	"patternProperties": {
-		function: patternProperties,
-	},
+// objectValidationFor returns the object keywords of a dialect. Before
+// 2019-09 dependencies covers dependentRequired and dependentSchemas, and
+// propertyNames exists since draft-06.
+func objectValidationFor(version int) map[string]rawValidation {
+	res := map[string]rawValidation{
+		"properties": {
+			compile: properties,
+		},
+		"required": {
+			function: required,
+		},
+		"dependentRequired": {
+			function: dependentRequired,
+		},
+		"dependentSchemas": {
+			compile: dependentSchemas,
+		},
+		"minProperties": {
+			function: minProperties,
+		},
+		"maxProperties": {
+			function: maxProperties,
+		},
+		"propertyNames": {
+			compile: propertyNames,
+		},
+		"patternProperties": {
+			compile: patternProperties,
+		},
+		"additionalProperties": {
+			compile:  additionalProperties,
+			optional: []string{"properties", "patternProperties"},
+		},
+	}
+
+	if version >= 2019 {
+		return res
+	}
+
+	delete(res, "dependentRequired")
+	delete(res, "dependentSchemas")
+
+	res["dependencies"] = rawValidation{
+		compile: dependencies,
+	}
+
+	if version < 6 {
+		delete(res, "propertyNames")
+	}
+
+	return res
 }
 
-func properties(value any) (func(a any) *Error, error) {
-	props := make(map[string]ValueType)
+func properties(c *compiler, value any) (validateFunc, error) {
+	values, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("properties requires object")
+	}
+
+	props := make(map[string]*Schema)
 
-	for name, value := range value.(map[string]any) {
-		valueType, ok := getValueType(value.(map[string]any)["type"])
-		if !ok {
-			return nil, errors.New("properties requires valid type")
+	for name, value := range values {
+		schema, err := c.compile(value, name)
+		if err != nil {
+			return nil, err
 		}
-		props[name] = valueType
+
+		props[name] = schema
 	}
 
-	return func(a any) *Error {
+	return func(a any, e *evaluated) *Error {
 		v := a.(map[string]any)
 		for name, value := range v {
-			err := validate(value, &Schema{
-				valueType: props[name],
-			})
+			schema, ok := props[name]
+			if !ok {
+				continue
+			}
+
+			err := e.validateAt(name, value, schema)
 			if err != nil {
 				return err
 			}
+
+			e.addProperty(name)
 		}
 		return nil
 	}, nil
 }
 
 func required(value any) (func(a any) *Error, error) {
+	names, ok := stringsOf(value)
+	if !ok {
+		return nil, errors.New("required requires array of strings")
+	}
+
 	return func(a any) *Error {
 		values := a.(map[string]any)
-		for _, name := range value.([]any) {
-			_, ok := values[name.(string)]
+		for _, name := range names {
+			_, ok := values[name]
 			if !ok {
-				return NewError(name.(string), "")
+				return NewError(name, "")
 			}
 		}
 		return nil
@@ -68,15 +113,20 @@ func required(value any) (func(a any) *Error, error) {
 }
 
 func dependentRequired(value any) (func(a any) *Error, error) {
-	props := make(map[string][]string)
+	values, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("dependentRequired requires object")
+	}
 
-	for name, values := range value.(map[string]any) {
-		temp := make([]string, 0)
+	props := make(map[string][]string)
 
-		for _, value := range values.([]any) {
-			temp = append(temp, value.(string))
+	for name, values := range values {
+		names, ok := stringsOf(values)
+		if !ok {
+			return nil, errors.New("dependentRequired requires object of arrays of strings")
 		}
-		props[name] = temp
+
+		props[name] = names
 	}
 
 	return func(a any) *Error {
@@ -98,6 +148,102 @@ func dependentRequired(value any) (func(a any) *Error, error) {
 	}, nil
 }
 
+func dependentSchemas(c *compiler, value any) (validateFunc, error) {
+	values, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("dependentSchemas requires object")
+	}
+
+	schemas := make(map[string]*Schema)
+
+	for name, value := range values {
+		schema, err := c.compileInPlace(value, name)
+		if err != nil {
+			return nil, err
+		}
+
+		schemas[name] = schema
+	}
+
+	return func(a any, e *evaluated) *Error {
+		v := a.(map[string]any)
+
+		for name, schema := range schemas {
+			if _, ok := v[name]; !ok {
+				continue
+			}
+
+			res, err := e.evaluate(a, schema)
+			if err != nil {
+				return err
+			}
+
+			e.merge(res)
+		}
+
+		return nil
+	}, nil
+}
+
+// dependencies combines dependentRequired and dependentSchemas before
+// 2019-09: each property maps either to required names or to a schema.
+func dependencies(c *compiler, value any) (validateFunc, error) {
+	values, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("dependencies requires object")
+	}
+
+	names := make(map[string]any)
+	schemas := make(map[string]any)
+
+	for name, value := range values {
+		if _, ok := value.([]any); ok {
+			names[name] = value
+		} else {
+			schemas[name] = value
+		}
+	}
+
+	validateRequired, err := dependentRequired(names)
+	if err != nil {
+		return nil, errors.New("dependencies requires array of strings or schema")
+	}
+
+	validateSchemas, err := dependentSchemas(c, schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(a any, e *evaluated) *Error {
+		err := validateRequired(a)
+		if err != nil {
+			return err
+		}
+
+		return validateSchemas(a, e)
+	}, nil
+}
+
+// stringsOf returns the strings of an array of strings.
+func stringsOf(value any) ([]string, bool) {
+	values, ok := value.([]any)
+	if !ok {
+		return nil, false
+	}
+
+	res := make([]string, 0, len(values))
+	for _, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+
+		res = append(res, str)
+	}
+
+	return res, true
+}
+
 func minProperties(value any) (func(a any) *Error, error) {
 	v, ok := value.(float64)
 	if !ok {
@@ -139,31 +285,15 @@ func maxProperties(value any) (func(a any) *Error, error) {
 	}, nil
 }
 
-func propertyNames(value any) (func(a any) *Error, error) {
-	values := value.(map[string]any)
-
-	var schema *Schema = &Schema{
-		valueType:    String,
-		validateFunc: make(map[string]validateFunc),
+func propertyNames(c *compiler, value any) (validateFunc, error) {
+	schema, err := c.compile(value)
+	if err != nil {
+		return nil, err
 	}
 
-	for name, validation := range stringValidation {
-		value, ok := values[name]
-		if !ok {
-			continue
-		}
-
-		validate, err := validation.function(value)
-		if err != nil {
-			return nil, err
-		}
-
-		schema.validateFunc[name] = validate
-	}
-
-	return func(a any) *Error {
+	return func(a any, e *evaluated) *Error {
 		for name := range a.(map[string]any) {
-			err := validate(name, schema)
+			err := e.validate(name, schema)
 			if err != nil {
 				return err
 			}
@@ -173,87 +303,117 @@ func propertyNames(value any) (func(a any) *Error, error) {
 	}, nil
 }
 
-func patternProperties(value any) (func(a any) *Error, error) {
-	values := value.(map[string]any)
-	schemas := make(map[*regexp.Regexp]*Schema)
+func patternProperties(c *compiler, value any) (validateFunc, error) {
+	values, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("patternProperties requires object")
+	}
 
-	for patter, values := range values {
-		v := values.(map[string]any)
+	schemas := make(map[regex]*Schema)
 
-		valueType, ok := getValueType(v["type"])
-		if !ok {
-			return nil, errors.New("maxContains requires valid type")
+	for pattern, value := range values {
+		schema, err := c.compile(value, pattern)
+		if err != nil {
+			return nil, err
 		}
 
-		var schema *Schema = &Schema{
-			valueType:    valueType,
-			validateFunc: make(map[string]validateFunc),
+		r, err := compileRegex(c.validator, pattern)
+		if err != nil {
+			return nil, err
 		}
 
-		switch valueType {
-		case Integer:
-			for name, validation := range integerValidation {
-				value, ok := v[name]
-				if !ok {
-					continue
-				}
+		schemas[r] = schema
+	}
 
-				validate, err := validation.function(value)
+	return func(a any, e *evaluated) *Error {
+		for name, target := range a.(map[string]any) {
+			for regex, schema := range schemas {
+				matched, err := regex.match(name)
 				if err != nil {
-					return nil, err
+					return NewError(regex, err.Error())
 				}
 
-				schema.validateFunc[name] = validate
-			}
-		case String:
-			for name, validation := range stringValidation {
-				value, ok := v[name]
-				if !ok {
-					continue
-				}
+				if matched {
+					err := e.validateAt(name, target, schema)
+					if err != nil {
+						return err
+					}
 
-				validate, err := validation.function(value)
-				if err != nil {
-					return nil, err
+					e.addProperty(name)
 				}
-
-				schema.validateFunc[name] = validate
 			}
-		case Number:
-			for name, validation := range numberValidation {
-				value, ok := v[name]
-				if !ok {
-					continue
-				}
 
-				validate, err := validation.function(value)
-				if err != nil {
-					return nil, err
-				}
-
-				schema.validateFunc[name] = validate
-			}
 		}
 
-		r, err := regexp.Compile(patter)
+		return nil
+	}, nil
+}
+
+func additionalProperties(c *compiler, value any) (validateFunc, error) {
+	v := value.([]any)
+
+	props, ok := v[1].(map[string]any)
+	if !ok && v[1] != nil {
+		return nil, errors.New("properties requires object")
+	}
+
+	patterns, ok := v[2].(map[string]any)
+	if !ok && v[2] != nil {
+		return nil, errors.New("patternProperties requires object")
+	}
+
+	regexps := make([]regex, 0, len(patterns))
+	for pattern := range patterns {
+		r, err := compileRegex(c.validator, pattern)
 		if err != nil {
 			return nil, err
 		}
 
-		schemas[r] = schema
+		regexps = append(regexps, r)
 	}
 
-	return func(a any) *Error {
-		for name, target := range a.(map[string]any) {
-			for regex, schema := range schemas {
-				if regex.MatchString(name) {
-					err := validate(target, schema)
-					if err != nil {
-						return err
-					}
+	schema, err := c.compile(v[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return func(a any, e *evaluated) *Error {
+		values := a.(map[string]any)
+
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+	loop:
+		for _, name := range names {
+			if _, ok := props[name]; ok {
+				continue
+			}
+
+			for _, r := range regexps {
+				matched, err := r.match(name)
+				if err != nil {
+					return NewError(r, err.Error())
 				}
+
+				if matched {
+					continue loop
+				}
+			}
+
+			if schema.falseSchema {
+				return NewError("no additional properties", name)
+			}
+
+			err := e.validateAt(name, values[name], schema)
+			if err != nil {
+				return NewError("valid additional property", name).SetCauses([]*Error{err})
 			}
 
+			e.addProperty(name)
 		}
 
 		return nil
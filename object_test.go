@@ -0,0 +1,56 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjectKeywordErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		schema  string
+		err     string
+	}{
+		{
+			name:    "dependencies with a number",
+			options: []Option{WithDialect(Draft7)},
+			schema:  `{"dependencies": {"a": [1]}}`,
+			err:     "dependencies requires array of strings",
+		},
+		{
+			name:   "dependencies with a number under the default dialect",
+			schema: `{"dependencies": {"a": ["b", null]}}`,
+			err:    "dependencies requires array of strings",
+		},
+		{
+			name:   "required with a string",
+			schema: `{"required": "a"}`,
+			err:    "required requires array of strings",
+		},
+		{
+			name:   "required with a number",
+			schema: `{"required": ["a", 1]}`,
+			err:    "required requires array of strings",
+		},
+		{
+			name:   "dependentRequired with a string",
+			schema: `{"dependentRequired": {"a": "b"}}`,
+			err:    "dependentRequired requires object of arrays of strings",
+		},
+		{
+			name:   "dependentRequired with an array",
+			schema: `{"dependentRequired": ["a"]}`,
+			err:    "dependentRequired requires object",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(tt.options...).Validate(`{"a": 1}`, tt.schema)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
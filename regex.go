@@ -0,0 +1,1483 @@
+package jsonschema
+
+import (
+	"errors"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// regex is a pattern compiled by either regex engine.
+type regex interface {
+	// match reports whether the pattern matches anywhere in s. It fails when
+	// the engine gives up on s.
+	match(s string) (bool, error)
+	String() string
+}
+
+// re2Regexp is a pattern compiled by the regexp package, which runs in linear
+// time and never gives up.
+type re2Regexp struct {
+	*regexp.Regexp
+}
+
+func (r re2Regexp) match(s string) (bool, error) {
+	return r.MatchString(s), nil
+}
+
+// compileRegex compiles a pattern of pattern, patternProperties or the regex
+// format with the regex engine of the validator.
+func compileRegex(v *Validator, pattern string) (regex, error) {
+	if v.regex == RegexECMA {
+		return compileECMA(pattern)
+	}
+
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) {
+			return nil, errors.New("pattern " + pattern + " is not supported by RE2: " + syntaxErr.Code.String() + ": " + syntaxErr.Expr)
+		}
+
+		return nil, errors.New("pattern " + pattern + " is not supported by RE2: " + err.Error())
+	}
+
+	return re2Regexp{r}, nil
+}
+
+// Limits of one match of the ECMA-262 engine. Backtracking can take
+// exponential time, so a match gives up after ecmaSteps steps plus
+// ecmaStepsPerRune for every code point of the input, or when it holds more
+// than ecmaMaxFrames frames on its backtrack stack.
+const (
+	ecmaSteps        = 1_000_000
+	ecmaStepsPerRune = 64
+	ecmaMaxFrames    = 1 << 21
+)
+
+var errBacktrackLimit = errors.New("backtracking limit exceeded")
+
+// ecmaRegexp is a pattern compiled by the ECMA-262 engine. It reads patterns
+// like a JavaScript RegExp with the u flag and matches them by backtracking
+// over code points.
+type ecmaRegexp struct {
+	source string
+	insts  []reInst
+	slots  int
+	//anchored patterns start with ^ and can only match at the start
+	anchored bool
+}
+
+func (r *ecmaRegexp) String() string {
+	return r.source
+}
+
+func (r *ecmaRegexp) match(s string) (bool, error) {
+	input := []rune(s)
+	m := &reMachine{
+		insts:  r.insts,
+		input:  input,
+		slots:  make([]int, r.slots),
+		budget: ecmaSteps + ecmaStepsPerRune*len(input),
+	}
+
+	for start := 0; start <= len(input); start++ {
+		for i := range m.slots {
+			m.slots[i] = -1
+		}
+
+		ok, err := m.run(0, start, -1)
+		if ok || err != nil {
+			return ok, err
+		}
+
+		if r.anchored {
+			break
+		}
+	}
+
+	return false, nil
+}
+
+type reOp uint8
+
+const (
+	//opChar matches one code point of set.
+	opChar reOp = iota
+	//opStar repeats opChar from min to max times with a single frame.
+	opStar
+	//opSplit goes on at x and at y on backtracking.
+	opSplit
+	opJump
+	//opGroupStart and opGroupEnd capture group x, starting at slot y.
+	opGroupStart
+	opGroupEnd
+	opAssert
+	opBackref
+	//opRepeatInit, opRepeat and opRepeatEnd repeat the instructions between
+	//opRepeat and opRepeatEnd from min to max times. Slot x counts the
+	//iterations and slot x+1 holds where the current one started. opRepeat
+	//goes on at y after the last iteration, opRepeatEnd goes back to y.
+	opRepeatInit
+	opRepeat
+	opRepeatEnd
+	//opLook runs the lookaround that follows it up to its opMatch, then goes
+	//on at y.
+	opLook
+	opMatch
+)
+
+type reInst struct {
+	op          reOp
+	set         reChar
+	assert      reAssert
+	x, y        int
+	min, max    int
+	first, last int
+	lazy        bool
+	behind      bool
+	negate      bool
+}
+
+type reFrameKind uint8
+
+const (
+	//frameChoice goes on at pc and a.
+	frameChoice reFrameKind = iota
+	//frameUndo restores slot pc to a.
+	frameUndo
+	//frameEnter starts an iteration of the opRepeat at pc at a.
+	frameEnter
+	//frameGreedy gives back the code points an opStar at pc consumed after
+	//a, one at a time, from b.
+	frameGreedy
+	//frameLazy makes the opStar at pc consume one more code point at a, b
+	//more at most or any number for -1.
+	frameLazy
+)
+
+type reFrame struct {
+	kind reFrameKind
+	pc   int
+	a, b int
+}
+
+// reMachine runs a compiled pattern. Choice points and the previous values of
+// the slots it changes go on one stack, so backtracking pops the stack
+// instead of recursing and the depth of the Go stack doesn't depend on the
+// input. Slots hold the start and end of every capture group, -1 while a
+// group captured nothing, and the state of the repeats.
+type reMachine struct {
+	insts  []reInst
+	input  []rune
+	slots  []int
+	stack  []reFrame
+	steps  int
+	budget int
+}
+
+// run matches from pc at pos until an opMatch, which has to be at target
+// unless target is -1. On failure it restores the slots and the stack.
+func (m *reMachine) run(pc, pos, target int) (bool, error) {
+	base := len(m.stack)
+
+	for {
+		m.steps++
+		if m.steps > m.budget || len(m.stack) > ecmaMaxFrames {
+			return false, errBacktrackLimit
+		}
+
+		inst := &m.insts[pc]
+		ok := true
+
+		switch inst.op {
+		case opChar:
+			ok = pos < len(m.input) && inst.set(m.input[pos])
+			pos++
+			pc++
+		case opStar:
+			start := pos
+			for pos-start < inst.min && pos < len(m.input) && inst.set(m.input[pos]) {
+				pos++
+			}
+
+			ok = pos-start == inst.min
+			if !ok {
+				break
+			}
+
+			more := -1
+			if inst.max >= 0 {
+				more = inst.max - inst.min
+			}
+
+			if inst.lazy {
+				m.stack = append(m.stack, reFrame{kind: frameLazy, pc: pc, a: pos, b: more})
+				pc++
+				break
+			}
+
+			for more != 0 && pos < len(m.input) && inst.set(m.input[pos]) {
+				pos++
+				more--
+			}
+
+			m.steps += pos - start
+			if pos > start+inst.min {
+				m.stack = append(m.stack, reFrame{kind: frameGreedy, pc: pc, a: start + inst.min, b: pos})
+			}
+
+			pc++
+		case opSplit:
+			m.stack = append(m.stack, reFrame{kind: frameChoice, pc: inst.y, a: pos})
+			pc = inst.x
+		case opJump:
+			pc = inst.x
+		case opGroupStart:
+			m.set(inst.y, pos)
+			pc++
+		case opGroupEnd:
+			m.set(2*inst.x, m.slots[inst.y])
+			m.set(2*inst.x+1, pos)
+			pc++
+		case opAssert:
+			ok = inst.assert.holds(m.input, pos)
+			pc++
+		case opBackref:
+			start, end := m.slots[2*inst.x], m.slots[2*inst.x+1]
+			if start >= 0 && end >= 0 {
+				ok = pos+end-start <= len(m.input) && slices.Equal(m.input[start:end], m.input[pos:pos+end-start])
+				pos += end - start
+			}
+
+			pc++
+		case opRepeatInit:
+			m.set(inst.x, 0)
+			pc++
+		case opRepeat:
+			count := m.slots[inst.x]
+
+			switch {
+			case inst.max >= 0 && count >= inst.max:
+				pc = inst.y
+			case count < inst.min:
+				m.enter(inst, pos)
+				pc++
+			case inst.lazy:
+				m.stack = append(m.stack, reFrame{kind: frameEnter, pc: pc, a: pos})
+				pc = inst.y
+			default:
+				m.stack = append(m.stack, reFrame{kind: frameChoice, pc: inst.y, a: pos})
+				m.enter(inst, pos)
+				pc++
+			}
+		case opRepeatEnd:
+			count := m.slots[inst.x]
+
+			//an optional iteration that matches nothing would loop forever
+			ok = pos != m.slots[inst.x+1] || count < inst.min
+			m.set(inst.x, count+1)
+			pc = inst.y
+		case opLook:
+			matched, err := m.look(inst, pc+1, pos)
+			if err != nil {
+				return false, err
+			}
+
+			ok = matched
+			pc = inst.y
+		case opMatch:
+			if target < 0 || pos == target {
+				return true, nil
+			}
+
+			ok = false
+		}
+
+		if !ok {
+			pc, pos, ok = m.backtrack(base)
+			if !ok {
+				return false, nil
+			}
+		}
+	}
+}
+
+// set changes a slot and saves its previous value for backtracking.
+func (m *reMachine) set(slot, value int) {
+	if m.slots[slot] == value {
+		return
+	}
+
+	m.stack = append(m.stack, reFrame{kind: frameUndo, pc: slot, a: m.slots[slot]})
+	m.slots[slot] = value
+}
+
+// enter starts an iteration of a repeat, which clears the groups inside it.
+func (m *reMachine) enter(inst *reInst, pos int) {
+	m.set(inst.x+1, pos)
+
+	for i := 2 * inst.first; i < 2*inst.last; i++ {
+		m.set(i, -1)
+	}
+}
+
+// backtrack pops the stack down to base until a frame gives another way to
+// go on.
+func (m *reMachine) backtrack(base int) (int, int, bool) {
+	for len(m.stack) > base {
+		f := m.stack[len(m.stack)-1]
+		m.stack = m.stack[:len(m.stack)-1]
+
+		switch f.kind {
+		case frameChoice:
+			return f.pc, f.a, true
+		case frameUndo:
+			m.slots[f.pc] = f.a
+		case frameEnter:
+			m.enter(&m.insts[f.pc], f.a)
+			return f.pc + 1, f.a, true
+		case frameGreedy:
+			pos := f.b - 1
+			if pos > f.a {
+				m.stack = append(m.stack, reFrame{kind: frameGreedy, pc: f.pc, a: f.a, b: pos})
+			}
+
+			return f.pc + 1, pos, true
+		case frameLazy:
+			if f.b == 0 || f.a >= len(m.input) || !m.insts[f.pc].set(m.input[f.a]) {
+				continue
+			}
+
+			more := f.b
+			if more > 0 {
+				more--
+			}
+
+			m.stack = append(m.stack, reFrame{kind: frameLazy, pc: f.pc, a: f.a + 1, b: more})
+			return f.pc + 1, f.a + 1, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// look runs a lookaround from pc. It doesn't backtrack into the lookaround
+// and keeps the captures of a positive match.
+func (m *reMachine) look(inst *reInst, pc, pos int) (bool, error) {
+	saved := slices.Clone(m.slots)
+	base := len(m.stack)
+
+	var matched bool
+	var err error
+	if inst.behind {
+		for start := pos; start >= 0 && !matched && err == nil; start-- {
+			matched, err = m.run(pc, start, pos)
+		}
+	} else {
+		matched, err = m.run(pc, pos, -1)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	m.stack = m.stack[:base]
+
+	//captures of a negative lookaround never match anything
+	if !matched || inst.negate {
+		copy(m.slots, saved)
+		return matched != inst.negate, nil
+	}
+
+	for i, value := range saved {
+		if m.slots[i] != value {
+			m.stack = append(m.stack, reFrame{kind: frameUndo, pc: i, a: value})
+		}
+	}
+
+	return true, nil
+}
+
+// reNode is a parsed pattern, which emits its instructions into a program.
+type reNode interface {
+	emit(p *reProgram)
+}
+
+type reProgram struct {
+	insts  []reInst
+	groups int
+	slots  int
+}
+
+func (p *reProgram) add(inst reInst) int {
+	p.insts = append(p.insts, inst)
+	return len(p.insts) - 1
+}
+
+type reSeq []reNode
+
+func (s reSeq) emit(p *reProgram) {
+	for _, node := range s {
+		node.emit(p)
+	}
+}
+
+type reAlt []reNode
+
+func (a reAlt) emit(p *reProgram) {
+	if set, ok := charSet(a); ok {
+		set.emit(p)
+		return
+	}
+
+	var jumps []int
+	for _, node := range a[:len(a)-1] {
+		split := p.add(reInst{op: opSplit, x: len(p.insts) + 1})
+		node.emit(p)
+		jumps = append(jumps, p.add(reInst{op: opJump}))
+		p.insts[split].y = len(p.insts)
+	}
+
+	a[len(a)-1].emit(p)
+
+	for _, jump := range jumps {
+		p.insts[jump].x = len(p.insts)
+	}
+}
+
+// reChar matches one code point.
+type reChar func(rune) bool
+
+func (c reChar) emit(p *reProgram) {
+	p.add(reInst{op: opChar, set: c})
+}
+
+// charSet returns the code points of a node that always matches exactly one
+// code point and captures nothing, like a|b, which can run as a single
+// opChar.
+func charSet(node reNode) (reChar, bool) {
+	switch node := node.(type) {
+	case reChar:
+		return node, true
+	case reSeq:
+		if len(node) == 1 {
+			return charSet(node[0])
+		}
+	case reAlt:
+		sets := make([]reChar, 0, len(node))
+		for _, alternative := range node {
+			set, ok := charSet(alternative)
+			if !ok {
+				return nil, false
+			}
+
+			sets = append(sets, set)
+		}
+
+		return func(r rune) bool {
+			for _, set := range sets {
+				if set(r) {
+					return true
+				}
+			}
+
+			return false
+		}, true
+	}
+
+	return nil, false
+}
+
+// reAssert matches the empty string where it holds.
+type reAssert int
+
+const (
+	assertStart reAssert = iota
+	assertEnd
+	assertWordBoundary
+	assertNotWordBoundary
+)
+
+func (a reAssert) emit(p *reProgram) {
+	p.add(reInst{op: opAssert, assert: a})
+}
+
+func (a reAssert) holds(input []rune, pos int) bool {
+	switch a {
+	case assertStart:
+		return pos == 0
+	case assertEnd:
+		return pos == len(input)
+	case assertWordBoundary:
+		return isWordBoundary(input, pos)
+	}
+
+	return !isWordBoundary(input, pos)
+}
+
+// anchored reports whether every match of node starts with ^.
+func anchored(node reNode) bool {
+	switch node := node.(type) {
+	case reAssert:
+		return node == assertStart
+	case reSeq:
+		return len(node) > 0 && anchored(node[0])
+	case reAlt:
+		for _, alternative := range node {
+			if !anchored(alternative) {
+				return false
+			}
+		}
+
+		return true
+	case *reGroup:
+		return anchored(node.node)
+	}
+
+	return false
+}
+
+type reGroup struct {
+	index int
+	node  reNode
+}
+
+func (g *reGroup) emit(p *reProgram) {
+	//the start waits in a slot of its own, the group captures at its end
+	start := 2*(p.groups+1) + g.index
+
+	p.add(reInst{op: opGroupStart, x: g.index, y: start})
+	g.node.emit(p)
+	p.add(reInst{op: opGroupEnd, x: g.index, y: start})
+}
+
+// reLook is a lookahead or lookbehind.
+type reLook struct {
+	node   reNode
+	behind bool
+	negate bool
+}
+
+func (l *reLook) emit(p *reProgram) {
+	look := p.add(reInst{op: opLook, behind: l.behind, negate: l.negate})
+	l.node.emit(p)
+	p.add(reInst{op: opMatch})
+	p.insts[look].y = len(p.insts)
+}
+
+type reBackref struct {
+	index int
+	name  string
+}
+
+func (b *reBackref) emit(p *reProgram) {
+	p.add(reInst{op: opBackref, x: b.index})
+}
+
+// reRepeat repeats node from min to max times, max -1 has no limit. Groups
+// first to last-1 are inside node and are cleared on every iteration.
+type reRepeat struct {
+	node        reNode
+	min, max    int
+	lazy        bool
+	first, last int
+}
+
+func (r *reRepeat) emit(p *reProgram) {
+	if set, ok := charSet(r.node); ok {
+		p.add(reInst{op: opStar, set: set, min: r.min, max: r.max, lazy: r.lazy})
+		return
+	}
+
+	slot := p.slots
+	p.slots += 2
+
+	p.add(reInst{op: opRepeatInit, x: slot})
+	head := p.add(reInst{op: opRepeat, x: slot, min: r.min, max: r.max, lazy: r.lazy, first: r.first, last: r.last})
+	r.node.emit(p)
+	p.add(reInst{op: opRepeatEnd, x: slot, y: head, min: r.min})
+	p.insts[head].y = len(p.insts)
+}
+
+// reError is a pattern the ECMA-262 engine can't compile, either because it
+// is not valid or because it uses something the engine doesn't support.
+type reError struct {
+	msg         string
+	unsupported bool
+}
+
+func (e *reError) Error() string {
+	return e.msg
+}
+
+// compileECMA compiles an ECMA-262 pattern.
+func compileECMA(pattern string) (*ecmaRegexp, error) {
+	p := &reParser{
+		src:   []rune(pattern),
+		names: make(map[string]int),
+	}
+
+	node, err := p.parseDisjunction()
+	if err == nil && p.more() {
+		err = p.invalid("unmatched )")
+	}
+
+	if err == nil {
+		err = p.resolveBackrefs()
+	}
+
+	if err != nil {
+		var reErr *reError
+		if errors.As(err, &reErr) && reErr.unsupported {
+			return nil, errors.New("pattern " + pattern + " is not supported: " + err.Error())
+		}
+
+		return nil, errors.New("invalid pattern " + pattern + ": " + err.Error())
+	}
+
+	prog := &reProgram{
+		groups: p.groups,
+		slots:  3 * (p.groups + 1),
+	}
+	node.emit(prog)
+	prog.add(reInst{op: opMatch})
+
+	return &ecmaRegexp{
+		source:   pattern,
+		insts:    prog.insts,
+		slots:    prog.slots,
+		anchored: anchored(node),
+	}, nil
+}
+
+type reParser struct {
+	src      []rune
+	pos      int
+	groups   int
+	names    map[string]int
+	backrefs []*reBackref
+}
+
+func (p *reParser) invalid(msg string) error {
+	return &reError{msg: msg + " at offset " + strconv.Itoa(p.pos)}
+}
+
+func (p *reParser) unsupported(msg string) error {
+	return &reError{msg: msg, unsupported: true}
+}
+
+func (p *reParser) more() bool {
+	return p.pos < len(p.src)
+}
+
+// peek returns the next code point, or -1 at the end of the pattern.
+func (p *reParser) peek() rune {
+	if !p.more() {
+		return -1
+	}
+
+	return p.src[p.pos]
+}
+
+func (p *reParser) eat(r rune) bool {
+	if p.peek() != r {
+		return false
+	}
+
+	p.pos++
+	return true
+}
+
+func (p *reParser) eatString(s string) bool {
+	runes := []rune(s)
+	if len(p.src)-p.pos < len(runes) || !slices.Equal(p.src[p.pos:p.pos+len(runes)], runes) {
+		return false
+	}
+
+	p.pos += len(runes)
+	return true
+}
+
+func (p *reParser) parseDisjunction() (reNode, error) {
+	var res reAlt
+
+	for {
+		alternative, err := p.parseAlternative()
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, alternative)
+
+		if !p.eat('|') {
+			break
+		}
+	}
+
+	if len(res) == 1 {
+		return res[0], nil
+	}
+
+	return res, nil
+}
+
+func (p *reParser) parseAlternative() (reNode, error) {
+	var res reSeq
+
+	for p.more() && p.peek() != '|' && p.peek() != ')' {
+		term, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, term)
+	}
+
+	return res, nil
+}
+
+func (p *reParser) parseTerm() (reNode, error) {
+	first := p.groups + 1
+
+	atom, quantifiable, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.ContainsRune("*+?{", p.peek()) {
+		return atom, nil
+	}
+
+	if !quantifiable {
+		return nil, p.invalid("nothing to repeat")
+	}
+
+	res := &reRepeat{
+		node:  atom,
+		max:   -1,
+		first: first,
+		last:  p.groups + 1,
+	}
+
+	switch {
+	case p.eat('*'):
+	case p.eat('+'):
+		res.min = 1
+	case p.eat('?'):
+		res.max = 1
+	default:
+		res.min, res.max, err = p.parseBraces()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res.lazy = p.eat('?')
+
+	return res, nil
+}
+
+// parseBraces parses the {n}, {n,} and {n,m} quantifiers.
+func (p *reParser) parseBraces() (int, int, error) {
+	p.pos++
+
+	min, ok, err := p.parseCount()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !ok {
+		return 0, 0, p.invalid("incomplete quantifier")
+	}
+
+	max := min
+	if p.eat(',') {
+		max, ok, err = p.parseCount()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if !ok {
+			max = -1
+		}
+	}
+
+	if !p.eat('}') {
+		return 0, 0, p.invalid("incomplete quantifier")
+	}
+
+	if max >= 0 && max < min {
+		return 0, 0, p.invalid("numbers out of order in quantifier")
+	}
+
+	return min, max, nil
+}
+
+func (p *reParser) parseCount() (int, bool, error) {
+	start := p.pos
+	for p.more() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+
+	if start == p.pos {
+		return 0, false, nil
+	}
+
+	res, err := strconv.Atoi(string(p.src[start:p.pos]))
+	if err != nil {
+		return 0, false, p.unsupported("quantifier " + string(p.src[start:p.pos]) + " is too large")
+	}
+
+	return res, true, nil
+}
+
+// parseAtom also reports whether the atom can take a quantifier, which
+// assertions can't.
+func (p *reParser) parseAtom() (reNode, bool, error) {
+	r := p.peek()
+	p.pos++
+
+	switch r {
+	case '^':
+		return assertStart, false, nil
+	case '$':
+		return assertEnd, false, nil
+	case '.':
+		return reChar(func(r rune) bool {
+			return !isLineTerminator(r)
+		}), true, nil
+	case '(':
+		return p.parseGroup()
+	case '[':
+		class, err := p.parseClass()
+		return class, true, err
+	case '\\':
+		switch {
+		case p.eat('b'):
+			return assertWordBoundary, false, nil
+		case p.eat('B'):
+			return assertNotWordBoundary, false, nil
+		}
+
+		return p.parseAtomEscape()
+	case '*', '+', '?', '{':
+		p.pos--
+		return nil, false, p.invalid("nothing to repeat")
+	case ']', '}':
+		p.pos--
+		return nil, false, p.invalid("lone " + string(r))
+	}
+
+	return literal(r), true, nil
+}
+
+func (p *reParser) parseGroup() (reNode, bool, error) {
+	var res reNode
+	quantifiable := true
+
+	switch {
+	case p.eatString("?="):
+		res = &reLook{}
+		quantifiable = false
+	case p.eatString("?!"):
+		res = &reLook{negate: true}
+		quantifiable = false
+	case p.eatString("?<="):
+		res = &reLook{behind: true}
+		quantifiable = false
+	case p.eatString("?<!"):
+		res = &reLook{behind: true, negate: true}
+		quantifiable = false
+	case p.eatString("?:"):
+	case p.eatString("?<"):
+		name, err := p.parseGroupName()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if _, ok := p.names[name]; ok {
+			return nil, false, p.invalid("duplicate group name " + name)
+		}
+
+		p.groups++
+		p.names[name] = p.groups
+		res = &reGroup{index: p.groups}
+	case p.eat('?'):
+		if strings.ContainsRune("ims-", p.peek()) {
+			return nil, false, p.unsupported("inline modifiers")
+		}
+
+		return nil, false, p.invalid("invalid group")
+	default:
+		p.groups++
+		res = &reGroup{index: p.groups}
+	}
+
+	node, err := p.parseDisjunction()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !p.eat(')') {
+		return nil, false, p.invalid("unterminated group")
+	}
+
+	switch group := res.(type) {
+	case *reLook:
+		group.node = node
+	case *reGroup:
+		group.node = node
+	default:
+		return node, true, nil
+	}
+
+	return res, quantifiable, nil
+}
+
+// parseGroupName parses the name of a named group or backreference up to
+// and including the closing >.
+func (p *reParser) parseGroupName() (string, error) {
+	start := p.pos
+	for p.more() && p.peek() != '>' {
+		r := p.peek()
+		if !(r == '$' || r == '_' || unicode.IsLetter(r) || (p.pos > start && unicode.In(r, unicode.Nd, unicode.Mn, unicode.Mc, unicode.Pc))) {
+			return "", p.invalid("invalid group name")
+		}
+
+		p.pos++
+	}
+
+	if start == p.pos || !p.eat('>') {
+		return "", p.invalid("invalid group name")
+	}
+
+	return string(p.src[start : p.pos-1]), nil
+}
+
+func (p *reParser) parseAtomEscape() (reNode, bool, error) {
+	if !p.more() {
+		return nil, false, p.invalid("\\ at end of pattern")
+	}
+
+	r := p.peek()
+	p.pos++
+
+	set, ok, err := p.parseSetEscape(r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if ok {
+		return reChar(set), true, nil
+	}
+
+	switch {
+	case r >= '1' && r <= '9':
+		p.pos--
+		index, _, err := p.parseCount()
+		if err != nil {
+			return nil, false, err
+		}
+
+		res := &reBackref{index: index}
+		p.backrefs = append(p.backrefs, res)
+		return res, true, nil
+	case r == 'k':
+		if !p.eat('<') {
+			return nil, false, p.invalid("invalid named reference")
+		}
+
+		name, err := p.parseGroupName()
+		if err != nil {
+			return nil, false, err
+		}
+
+		res := &reBackref{name: name}
+		p.backrefs = append(p.backrefs, res)
+		return res, true, nil
+	}
+
+	c, err := p.parseCharEscape(r, false)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return literal(c), true, nil
+}
+
+// resolveBackrefs checks backreferences once all groups are known, they may
+// refer to groups after them.
+func (p *reParser) resolveBackrefs() error {
+	for _, backref := range p.backrefs {
+		if backref.name != "" {
+			index, ok := p.names[backref.name]
+			if !ok {
+				return &reError{msg: "reference to unknown group " + backref.name}
+			}
+
+			backref.index = index
+		}
+
+		if backref.index > p.groups {
+			return &reError{msg: "reference to unknown group " + strconv.Itoa(backref.index)}
+		}
+	}
+
+	return nil
+}
+
+// parseSetEscape parses the escapes that match a set of code points, it
+// returns false for other escapes.
+func (p *reParser) parseSetEscape(r rune) (func(rune) bool, bool, error) {
+	var res func(rune) bool
+
+	switch r {
+	case 'd', 'D':
+		res = isECMADigit
+	case 'w', 'W':
+		res = isECMAWord
+	case 's', 'S':
+		res = isECMASpace
+	case 'p', 'P':
+		var err error
+		res, err = p.parseProperty()
+		if err != nil {
+			return nil, false, err
+		}
+	default:
+		return nil, false, nil
+	}
+
+	if unicode.IsUpper(r) {
+		return func(c rune) bool {
+			return !res(c)
+		}, true, nil
+	}
+
+	return res, true, nil
+}
+
+func (p *reParser) parseCharEscape(r rune, inClass bool) (rune, error) {
+	switch r {
+	case 't':
+		return '\t', nil
+	case 'n':
+		return '\n', nil
+	case 'v':
+		return '\v', nil
+	case 'f':
+		return '\f', nil
+	case 'r':
+		return '\r', nil
+	case 'c':
+		c := p.peek()
+		if c < 'A' || c > 'z' || (c > 'Z' && c < 'a') {
+			return 0, p.invalid("invalid control escape")
+		}
+
+		p.pos++
+		return c % 32, nil
+	case '0':
+		if c := p.peek(); c >= '0' && c <= '9' {
+			return 0, p.invalid("invalid decimal escape")
+		}
+
+		return 0, nil
+	case 'x':
+		return p.parseHex(2)
+	case 'u':
+		return p.parseUnicodeEscape()
+	case 'b':
+		if inClass {
+			return '\b', nil
+		}
+	case '-':
+		if inClass {
+			return '-', nil
+		}
+	}
+
+	if strings.ContainsRune(`^$\.*+?()[]{}|/`, r) {
+		return r, nil
+	}
+
+	return 0, p.invalid("invalid escape \\" + string(r))
+}
+
+func (p *reParser) parseHex(digits int) (rune, error) {
+	if len(p.src)-p.pos < digits {
+		return 0, p.invalid("invalid hexadecimal escape")
+	}
+
+	res, err := strconv.ParseUint(string(p.src[p.pos:p.pos+digits]), 16, 32)
+	if err != nil {
+		return 0, p.invalid("invalid hexadecimal escape")
+	}
+
+	p.pos += digits
+	return rune(res), nil
+}
+
+// parseUnicodeEscape parses \u{...} and \uXXXX, joining a surrogate pair
+// written as two escapes into one code point.
+func (p *reParser) parseUnicodeEscape() (rune, error) {
+	if p.eat('{') {
+		start := p.pos
+		for p.more() && p.peek() != '}' {
+			p.pos++
+		}
+
+		res, err := strconv.ParseUint(string(p.src[start:p.pos]), 16, 32)
+		if err != nil || res > unicode.MaxRune || !p.eat('}') {
+			return 0, p.invalid("invalid Unicode escape")
+		}
+
+		return rune(res), nil
+	}
+
+	res, err := p.parseHex(4)
+	if err != nil {
+		return 0, p.invalid("invalid Unicode escape")
+	}
+
+	if res < 0xD800 || res > 0xDBFF || !p.eatString(`\u`) {
+		return res, nil
+	}
+
+	low, err := p.parseHex(4)
+	if err != nil || low < 0xDC00 || low > 0xDFFF {
+		p.pos -= 2
+		if err == nil {
+			p.pos -= 4
+		}
+
+		return res, nil
+	}
+
+	return 0x10000 + (res-0xD800)<<10 + (low - 0xDC00), nil
+}
+
+func (p *reParser) parseClass() (reNode, error) {
+	negate := p.eat('^')
+
+	var items []func(rune) bool
+
+	for !p.eat(']') {
+		if !p.more() {
+			return nil, p.invalid("unterminated character class")
+		}
+
+		lo, loSet, err := p.parseClassAtom()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.peek() != '-' || p.pos+1 >= len(p.src) || p.src[p.pos+1] == ']' {
+			if loSet == nil {
+				loSet = literal(lo)
+			}
+
+			items = append(items, loSet)
+			continue
+		}
+
+		p.pos++
+
+		hi, hiSet, err := p.parseClassAtom()
+		if err != nil {
+			return nil, err
+		}
+
+		if loSet != nil || hiSet != nil {
+			return nil, p.invalid("invalid character class range")
+		}
+
+		if lo > hi {
+			return nil, p.invalid("range out of order in character class")
+		}
+
+		items = append(items, func(r rune) bool {
+			return r >= lo && r <= hi
+		})
+	}
+
+	return reChar(func(r rune) bool {
+		for _, item := range items {
+			if item(r) {
+				return !negate
+			}
+		}
+
+		return negate
+	}), nil
+}
+
+// parseClassAtom returns either a code point or a set of them.
+func (p *reParser) parseClassAtom() (rune, func(rune) bool, error) {
+	r := p.peek()
+	p.pos++
+
+	if r != '\\' {
+		return r, nil, nil
+	}
+
+	if !p.more() {
+		return 0, nil, p.invalid("\\ at end of pattern")
+	}
+
+	r = p.peek()
+	p.pos++
+
+	set, ok, err := p.parseSetEscape(r)
+	if err != nil || ok {
+		return 0, set, err
+	}
+
+	c, err := p.parseCharEscape(r, true)
+	return c, nil, err
+}
+
+// parseProperty parses the {...} of \p and \P. It knows the general
+// categories, the scripts and the binary properties Go has tables for.
+func (p *reParser) parseProperty() (func(rune) bool, error) {
+	if !p.eat('{') {
+		return nil, p.invalid("invalid property name")
+	}
+
+	start := p.pos
+	for p.more() && p.peek() != '}' {
+		p.pos++
+	}
+
+	if !p.more() || start == p.pos {
+		return nil, p.invalid("invalid property name")
+	}
+
+	expr := string(p.src[start:p.pos])
+	p.pos++
+
+	name, value, ok := strings.Cut(expr, "=")
+	if !ok {
+		if res := generalCategory(expr); res != nil {
+			return res, nil
+		}
+
+		if res := binaryProperty(expr); res != nil {
+			return res, nil
+		}
+
+		return nil, p.unsupported("unknown Unicode property " + expr)
+	}
+
+	switch name {
+	case "General_Category", "gc":
+		if res := generalCategory(value); res != nil {
+			return res, nil
+		}
+	case "Script", "sc":
+		if table, ok := unicode.Scripts[value]; ok {
+			return func(r rune) bool {
+				return unicode.Is(table, r)
+			}, nil
+		}
+	case "Script_Extensions", "scx":
+		return nil, p.unsupported("Script_Extensions has no Unicode tables")
+	}
+
+	return nil, p.unsupported("unknown Unicode property " + expr)
+}
+
+// generalCategories maps the long names of general categories to the short
+// names of the unicode package.
+var generalCategories = map[string]string{
+	"Letter":                "L",
+	"Cased_Letter":          "LC",
+	"Uppercase_Letter":      "Lu",
+	"Lowercase_Letter":      "Ll",
+	"Titlecase_Letter":      "Lt",
+	"Modifier_Letter":       "Lm",
+	"Other_Letter":          "Lo",
+	"Mark":                  "M",
+	"Combining_Mark":        "M",
+	"Nonspacing_Mark":       "Mn",
+	"Spacing_Mark":          "Mc",
+	"Enclosing_Mark":        "Me",
+	"Number":                "N",
+	"Decimal_Number":        "Nd",
+	"digit":                 "Nd",
+	"Letter_Number":         "Nl",
+	"Other_Number":          "No",
+	"Punctuation":           "P",
+	"punct":                 "P",
+	"Connector_Punctuation": "Pc",
+	"Dash_Punctuation":      "Pd",
+	"Open_Punctuation":      "Ps",
+	"Close_Punctuation":     "Pe",
+	"Initial_Punctuation":   "Pi",
+	"Final_Punctuation":     "Pf",
+	"Other_Punctuation":     "Po",
+	"Symbol":                "S",
+	"Math_Symbol":           "Sm",
+	"Currency_Symbol":       "Sc",
+	"Modifier_Symbol":       "Sk",
+	"Other_Symbol":          "So",
+	"Separator":             "Z",
+	"Space_Separator":       "Zs",
+	"Line_Separator":        "Zl",
+	"Paragraph_Separator":   "Zp",
+	"Other":                 "C",
+	"Control":               "Cc",
+	"cntrl":                 "Cc",
+	"Format":                "Cf",
+	"Surrogate":             "Cs",
+	"Private_Use":           "Co",
+	"Unassigned":            "Cn",
+}
+
+func generalCategory(name string) func(rune) bool {
+	if short, ok := generalCategories[name]; ok {
+		name = short
+	}
+
+	switch name {
+	case "LC":
+		return func(r rune) bool {
+			return unicode.In(r, unicode.Lu, unicode.Ll, unicode.Lt)
+		}
+	case "Cn":
+		return func(r rune) bool {
+			return !isAssigned(r)
+		}
+	case "C":
+		//the unicode package leaves unassigned code points out of C
+		return func(r rune) bool {
+			return unicode.Is(unicode.C, r) || !isAssigned(r)
+		}
+	}
+
+	table, ok := unicode.Categories[name]
+	if !ok {
+		return nil
+	}
+
+	return func(r rune) bool {
+		return unicode.Is(table, r)
+	}
+}
+
+// ecmaProperties are the binary properties of ECMA-262 that the unicode
+// package has a table for.
+var ecmaProperties = []string{
+	"ASCII_Hex_Digit", "Bidi_Control", "Dash", "Deprecated", "Diacritic",
+	"Extender", "Hex_Digit", "IDS_Binary_Operator", "IDS_Trinary_Operator",
+	"Ideographic", "Join_Control", "Logical_Order_Exception",
+	"Noncharacter_Code_Point", "Pattern_Syntax", "Pattern_White_Space",
+	"Quotation_Mark", "Radical", "Regional_Indicator", "Sentence_Terminal",
+	"Soft_Dotted", "Terminal_Punctuation", "Unified_Ideograph",
+	"Variation_Selector", "White_Space",
+}
+
+func binaryProperty(name string) func(rune) bool {
+	if slices.Contains(ecmaProperties, name) {
+		table := unicode.Properties[name]
+		return func(r rune) bool {
+			return unicode.Is(table, r)
+		}
+	}
+
+	switch name {
+	case "Any":
+		return func(rune) bool {
+			return true
+		}
+	case "ASCII":
+		return func(r rune) bool {
+			return r <= unicode.MaxASCII
+		}
+	case "Assigned":
+		return isAssigned
+	case "Alphabetic":
+		return func(r rune) bool {
+			return unicode.In(r, unicode.L, unicode.Nl, unicode.Other_Alphabetic)
+		}
+	case "Lowercase":
+		return func(r rune) bool {
+			return unicode.In(r, unicode.Ll, unicode.Other_Lowercase)
+		}
+	case "Uppercase":
+		return func(r rune) bool {
+			return unicode.In(r, unicode.Lu, unicode.Other_Uppercase)
+		}
+	case "Math":
+		return func(r rune) bool {
+			return unicode.In(r, unicode.Sm, unicode.Other_Math)
+		}
+	case "Grapheme_Extend":
+		return func(r rune) bool {
+			return unicode.In(r, unicode.Mn, unicode.Me, unicode.Other_Grapheme_Extend)
+		}
+	case "Extended_Pictographic":
+		return func(r rune) bool {
+			return unicode.Is(extendedPictographic, r)
+		}
+	case "ID_Start":
+		return isIDStart
+	case "ID_Continue":
+		return func(r rune) bool {
+			return isIDStart(r) || (unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) &&
+				!unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space))
+		}
+	}
+
+	return nil
+}
+
+func isIDStart(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.Nl, unicode.Other_ID_Start) &&
+		!unicode.In(r, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
+}
+
+func isAssigned(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Z, unicode.C)
+}
+
+func literal(c rune) reChar {
+	return func(r rune) bool {
+		return r == c
+	}
+}
+
+// isECMADigit is \d, which is ASCII only even with the u flag.
+func isECMADigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isECMAWord is \w, which is ASCII only even with the u flag.
+func isECMAWord(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || isECMADigit(r) || r == '_'
+}
+
+// isECMASpace is \s, the white space and line terminators of ECMA-262.
+func isECMASpace(r rune) bool {
+	switch r {
+	case '\t', '\v', '\f', ' ', 0xA0, 0xFEFF:
+		return true
+	}
+
+	return isLineTerminator(r) || unicode.Is(unicode.Zs, r)
+}
+
+func isLineTerminator(r rune) bool {
+	return r == '\n' || r == '\r' || r == 0x2028 || r == 0x2029
+}
+
+func isWordBoundary(input []rune, pos int) bool {
+	before := pos > 0 && isECMAWord(input[pos-1])
+	after := pos < len(input) && isECMAWord(input[pos])
+
+	return before != after
+}
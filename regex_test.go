@@ -0,0 +1,256 @@
+package jsonschema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestECMAMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{`a|`, "b", true},
+		{`^a|b$`, "cb", true},
+		{`^(?:a|b)*$`, "abba", true},
+		{`^(?:a|b)*$`, "abca", false},
+		{`^a{2,3}$`, "aaaa", false},
+		{`^a{2,}?b$`, "aaab", true},
+		{`^(?:ab){2}$`, "abab", true},
+		{`^(?:ab)+?c$`, "ababc", true},
+		{`^(a*)*$`, "aaa", true},
+		{`^(a*)*b$`, "aaa", false},
+		{`^(a|ab)(c|bcd)(d*)$`, "abcd", true},
+		{`^.$`, "\n", false},
+		{`^.$`, "\U0001F600", true},
+		{`^\u{1F600}$`, "\U0001F600", true},
+		{`^\cJ$`, "\n", true},
+		{`^\x41B\0$`, "AB\x00", true},
+		{`\bfoo\b`, "a foo.", true},
+		{`\bfoo\b`, "afoo", false},
+		{`\Bfoo`, "afoo", true},
+
+		//\d and \w are ASCII only, \s follows ECMA-262
+		{`^\d$`, "\u07C0", false},
+		{`^\w$`, "\u00E9", false},
+		{`^\s$`, "\u00A0", true},
+		{`^\s$`, "\uFEFF", true},
+		{`^\S$`, "\u2028", false},
+
+		//lookarounds
+		{`a(?=b)`, "ab", true},
+		{`a(?=b)`, "ac", false},
+		{`a(?!b)`, "ab", false},
+		{`a(?!b)`, "ac", true},
+		{`(?<=\$)\d+`, "$42", true},
+		{`(?<=\$)\d+`, "42", false},
+		{`(?<!a)b`, "ab", false},
+		{`(?<!a)b`, "cb", true},
+		{`^(?=(a+))a*b\1$`, "aaabaaa", true},
+		{`^(?!(a))\1b$`, "b", true},
+		{`(?<=^(a+))b`, "aab", true},
+
+		//backreferences
+		{`^(a)\1$`, "aa", true},
+		{`^(a)\1$`, "ab", false},
+		{`^(?<x>ab)\k<x>$`, "abab", true},
+		{`^(?<x>ab)\k<x>$`, "abba", false},
+		{`^\1(a)$`, "a", true},
+		{`^(?:(a)|b)\1$`, "b", true},
+		{`^(?:(a)|b)+\1$`, "ab", true},
+		{`^(a)(b)(c)(d)(e)(f)(g)(h)(i)(j)\10$`, "abcdefghijj", true},
+
+		//classes
+		{`^[a-c]+$`, "abc", true},
+		{`^[a-c]+$`, "abd", false},
+		{`^[^a-c]$`, "d", true},
+		{`^[\d-]+$`, "1-2", true},
+		{`^[-a]$`, "-", true},
+		{`^[\u{1F600}-\u{1F64F}]$`, "\U0001F610", true},
+		{`^[]$`, "a", false},
+		{`^[^]$`, "\n", true},
+		{`^[\b]$`, "\b", true},
+
+		//Unicode properties
+		{`^\p{L}+$`, "\u00E9t\u00E9", true},
+		{`^\p{Letter}$`, "1", false},
+		{`^\P{L}$`, "1", true},
+		{`^\P{L}$`, "a", false},
+		{`^\p{Lu}$`, "A", true},
+		{`^\p{General_Category=Decimal_Number}$`, "\u0663", true},
+		{`^\p{Script=Greek}$`, "\u03B1", true},
+		{`^\p{sc=Latin}$`, "\u03B1", false},
+		{`^\p{ASCII_Hex_Digit}$`, "f", true},
+		{`^[\p{Nd}x]+$`, "1x2", true},
+		{`^[^\P{Nd}]$`, "1", true},
+
+		//surrogate pairs are one code point
+		{"^\U0001F600$", "\U0001F600", true},
+		{"^[\U0001F600]$", "\U0001F600", true},
+		{`^\uD83D$`, "\U0001F600", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.s, func(t *testing.T) {
+			r, err := compileECMA(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := r.match(tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("match(%+q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileECMAError(t *testing.T) {
+	tests := []struct {
+		pattern string
+		err     string
+	}{
+		{`\a`, "invalid pattern \\a: invalid escape \\a at offset 2"},
+		{`(`, "invalid pattern (: unterminated group at offset 1"},
+		{`)`, "invalid pattern ): unmatched ) at offset 0"},
+		{`a**`, "invalid pattern a**: nothing to repeat at offset 2"},
+		{`{`, "invalid pattern {: nothing to repeat at offset 0"},
+		{`a{2,1}`, "invalid pattern a{2,1}: numbers out of order in quantifier"},
+		{`(?=a)*`, "invalid pattern (?=a)*: nothing to repeat"},
+		{`\1`, "invalid pattern \\1: "},
+		{`\k<x>`, "invalid pattern \\k<x>: "},
+		{`(?<x>a)(?<x>b)`, "invalid pattern (?<x>a)(?<x>b): duplicate group name x"},
+		{`\c1`, "invalid pattern \\c1: "},
+		{`\u{110000}`, "invalid pattern \\u{110000}: "},
+		{`[b-a]`, "invalid pattern [b-a]: "},
+		{`[\d-z]`, "invalid pattern [\\d-z]: "},
+		{`[a-\d]`, "invalid pattern [a-\\d]: "},
+		{`[a`, "invalid pattern [a: "},
+		{`\p{Foo}`, "pattern \\p{Foo} is not supported: unknown Unicode property Foo"},
+		{`\p{scx=Latn}`, "pattern \\p{scx=Latn} is not supported: "},
+		{`(?i)a`, "pattern (?i)a is not supported: inline modifiers"},
+		{`(?i:a)`, "pattern (?i:a) is not supported: inline modifiers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			_, err := compileECMA(tt.pattern)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestECMALimits(t *testing.T) {
+	long := strings.Repeat("ab", 5<<20/2)
+
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+		err     error
+	}{
+		//repeats of single code points take one frame, whatever the length
+		{`^(?:a|b)*$`, long, true, nil},
+		{`^[ab]*?$`, long, true, nil},
+		{`^a*$`, strings.Repeat("a", 1<<20), true, nil},
+		//other repeats take frames for every iteration and give up
+		{`^(?:ab)*$`, long, false, errBacktrackLimit},
+		{`^(a|b)*$`, long, false, errBacktrackLimit},
+		//exponential backtracking gives up
+		{`^(a+)+$`, strings.Repeat("a", 40) + "b", false, errBacktrackLimit},
+		{`(a|b)*c`, long, false, errBacktrackLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			r, err := compileECMA(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := r.match(tt.s)
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("got %v, %v, want %v, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestECMAValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		instance string
+		//err is a part of the expected error, empty for valid instances
+		err string
+	}{
+		{
+			name:     "pattern",
+			schema:   `{"pattern": "^(?<x>a)\\k<x>$"}`,
+			instance: `"aa"`,
+		},
+		{
+			name:     "pattern failing",
+			schema:   `{"pattern": "(?<!a)b"}`,
+			instance: `"ab"`,
+			err:      "failed to validate pattern; got: ab, expected: (?<!a)b",
+		},
+		{
+			name:     "pattern giving up",
+			schema:   `{"pattern": "^(a+)+$"}`,
+			instance: `"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaab"`,
+			err:      "got: backtracking limit exceeded",
+		},
+		{
+			name:     "patternProperties",
+			schema:   `{"patternProperties": {"^\\p{Lu}": {"type": "integer"}}}`,
+			instance: "{\"\u00C9t\u00E9\": 1, \"a\": \"b\"}",
+		},
+		{
+			name:     "patternProperties failing",
+			schema:   `{"patternProperties": {"^\\p{Lu}": {"type": "integer"}}}`,
+			instance: "{\"\u00C9t\u00E9\": \"b\"}",
+			err:      "expected: integer",
+		},
+		{
+			name:     "additionalProperties",
+			schema:   `{"patternProperties": {"^x-(?!internal)": true}, "additionalProperties": false}`,
+			instance: `{"x-public": 1}`,
+		},
+		{
+			name:     "additionalProperties failing",
+			schema:   `{"patternProperties": {"^x-(?!internal)": true}, "additionalProperties": false}`,
+			instance: `{"x-internal": 1}`,
+			err:      "got: x-internal, expected: no additional properties",
+		},
+		{
+			name:     "unsupported pattern",
+			schema:   `{"patternProperties": {"(?i)a": true}}`,
+			instance: `{}`,
+			err:      "pattern (?i)a is not supported: inline modifiers",
+		},
+	}
+
+	v := NewValidator(WithRegexEngine(RegexECMA))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(tt.instance, tt.schema)
+
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("got %v, want valid", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
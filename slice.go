@@ -3,266 +3,282 @@ package jsonschema
 import (
 	"errors"
 	"fmt"
-	"reflect"
+	"strconv"
 )
 
-var sliceValidation map[string]rawValidation = map[string]rawValidation{
-	"minItems": {
-		function: minItems,
-	},
-	"maxItems": {
-		function: maxItems,
-	},
-	"uniqueItems": {
-		function: uniqueItems,
-	},
-	"contains": {
-		function: contains,
-	},
-	"minContains": {
-		function: minContains,
-		requires: []string{"contains"},
-	},
-	"maxContains": {
-		function: maxContains,
-		requires: []string{"contains"},
-	},
-	"items": {
-		function: items,
-	},
-}
+// sliceValidationFor returns the array keywords of a dialect. prefixItems
+// replaces the array form of items in 2020-12, minContains and maxContains
+// exist since 2019-09 and contains since draft-06.
+func sliceValidationFor(version int) map[string]rawValidation {
+	res := map[string]rawValidation{
+		"minItems": {
+			function: minItems,
+		},
+		"maxItems": {
+			function: maxItems,
+		},
+		"uniqueItems": {
+			function: uniqueItems,
+		},
+		"contains": {
+			compile:  contains,
+			optional: []string{"minContains", "maxContains"},
+		},
+		"prefixItems": {
+			compile: prefixItems,
+		},
+		"items": {
+			compile:  items,
+			optional: []string{"prefixItems"},
+		},
+	}
 
-// TODO: supports only slice
-func items(value any) (func(a any) *Error, error) {
-	slice, ok := value.([]any)
-	if ok {
-		return itemsSlice(slice)
+	if version >= 2020 {
+		return res
+	}
+
+	delete(res, "prefixItems")
+
+	res["items"] = rawValidation{
+		compile: itemsLegacy,
+	}
+
+	res["additionalItems"] = rawValidation{
+		compile:  additionalItems,
+		optional: []string{"items"},
 	}
 
-	v, ok := value.(map[string]any)
-	if ok {
-		return itemsMap(v)
+	switch {
+	case version < 6:
+		delete(res, "contains")
+	case version < 2019:
+		res["contains"] = rawValidation{
+			compile: containsLegacy,
+		}
 	}
 
-	return nil, errors.New("items supports map or slice only")
+	return res
 }
 
-func itemsSlice(value []any) (func(a any) *Error, error) {
-	var res []ValueType = make([]ValueType, 0, len(value))
+func prefixItems(c *compiler, value any) (validateFunc, error) {
+	slice, ok := value.([]any)
+	if !ok || len(slice) == 0 {
+		return nil, errors.New("prefixItems requires non-empty array of schemas")
+	}
 
-	for _, value := range value {
-		valueType, ok := getValueType(value.(map[string]any)["type"])
-		if !ok {
-			return nil, errors.New("maxContains requires valid type")
-		}
+	return itemsSlice(c, slice)
+}
 
-		res = append(res, valueType)
+// items applies one schema to every element after prefixItems.
+func items(c *compiler, value any) (validateFunc, error) {
+	v := value.([]any)
+
+	if _, ok := v[0].([]any); ok {
+		return nil, errors.New("items requires schema, tuples use prefixItems")
 	}
 
-	return func(a any) *Error {
-		for i, valueType := range res {
-			err := validate(a.([]any)[i], &Schema{
-				valueType: valueType,
-			})
-			if err != nil {
-				return NewError(valueType, a.([]any)[i])
-			}
+	var start int
+
+	if v[1] != nil {
+		prefix, ok := v[1].([]any)
+		if !ok {
+			return nil, errors.New("prefixItems requires non-empty array of schemas")
 		}
 
-		return nil
-	}, nil
+		start = len(prefix)
+	}
+
+	return itemsMap(c, v[0], start)
 }
 
-func itemsMap(values map[string]any) (func(a any) *Error, error) {
-	valueType, ok := getValueType(values["type"])
-	if !ok {
-		return nil, errors.New("maxContains requires valid type")
+// itemsLegacy is items before 2020-12, where an array of schemas validates a
+// tuple, like prefixItems does.
+func itemsLegacy(c *compiler, value any) (validateFunc, error) {
+	if slice, ok := value.([]any); ok {
+		return itemsSlice(c, slice)
 	}
 
-	var schema *Schema = &Schema{
-		valueType:    valueType,
-		validateFunc: make(map[string]validateFunc),
+	return itemsMap(c, value, 0)
+}
+
+// itemsDefault is items of the default dialect, which also takes the array
+// form of draft-07 when there is no prefixItems.
+func itemsDefault(c *compiler, value any) (validateFunc, error) {
+	v := value.([]any)
+
+	if tuple, ok := v[0].([]any); ok && v[1] == nil {
+		return itemsSlice(c, tuple)
 	}
 
-	switch valueType {
-	case Integer:
-		for name, validation := range integerValidation {
-			value, ok := values[name]
-			if !ok {
-				continue
-			}
+	return items(c, value)
+}
 
-			validate, err := validation.function(value)
-			if err != nil {
-				return nil, err
-			}
+// additionalItems applies to the elements after a draft-07 array form items
+// and is ignored otherwise.
+func additionalItems(c *compiler, value any) (validateFunc, error) {
+	v := value.([]any)
 
-			schema.validateFunc[name] = validate
-		}
-	case String:
-		for name, validation := range stringValidation {
-			value, ok := values[name]
-			if !ok {
-				continue
-			}
+	tuple, ok := v[1].([]any)
+	if !ok {
+		return func(a any, _ *evaluated) *Error {
+			return nil
+		}, nil
+	}
 
-			validate, err := validation.function(value)
-			if err != nil {
-				return nil, err
+	//false limits the length, so the error shows it
+	if additional, ok := v[0].(bool); ok && !additional {
+		return func(a any, _ *evaluated) *Error {
+			if len(a.([]any)) <= len(tuple) {
+				return nil
 			}
 
-			schema.validateFunc[name] = validate
-		}
-	case Number:
-		for name, validation := range numberValidation {
-			value, ok := values[name]
-			if !ok {
-				continue
-			}
+			return NewError(len(tuple), len(a.([]any)))
+		}, nil
+	}
 
-			validate, err := validation.function(value)
-			if err != nil {
-				return nil, err
-			}
+	return itemsMap(c, v[0], len(tuple))
+}
+
+// itemsSlice validates each element against the schema at the same index.
+// Elements past the end of the tuple, and missing ones, are not checked.
+func itemsSlice(c *compiler, value []any) (validateFunc, error) {
+	var res []*Schema = make([]*Schema, 0, len(value))
 
-			schema.validateFunc[name] = validate
+	for i, value := range value {
+		schema, err := c.compile(value, strconv.Itoa(i))
+		if err != nil {
+			return nil, err
 		}
+
+		res = append(res, schema)
 	}
 
-	return func(a any) *Error {
-		for _, target := range a.([]any) {
-			err := validate(target, schema)
+	return func(a any, e *evaluated) *Error {
+		v := a.([]any)
+
+		for i, schema := range res {
+			if i >= len(v) {
+				break
+			}
+
+			err := e.validateAt(strconv.Itoa(i), v[i], schema)
 			if err != nil {
 				return err
 			}
+
+			e.addItem(i)
 		}
 
 		return nil
 	}, nil
 }
 
-func maxContains(value any) (func(a any) *Error, error) {
-	if len(value.([]any)) < 2 {
-		return nil, errors.New("maxContains requires 2 value")
-	}
-
-	v := value.([]any)
-
-	checkType, ok := v[1].(map[string]any)
-	if !ok {
-		return nil, errors.New("maxContains requires type")
-	}
-
-	valueType, ok := getValueType(checkType["type"])
-	if !ok {
-		return nil, errors.New("maxContains requires valid type")
+// itemsMap validates every element from index start on against one schema.
+func itemsMap(c *compiler, value any, start int) (validateFunc, error) {
+	schema, err := c.compile(value)
+	if err != nil {
+		return nil, err
 	}
 
-	checkMax, ok := v[0].(float64)
-	if !ok {
-		return nil, errors.New("maxContains requires number")
-	}
-
-	return func(a any) *Error {
-		var correct int
+	return func(a any, e *evaluated) *Error {
+		v := a.([]any)
 
-		for _, elem := range a.([]any) {
-			err := validate(elem, &Schema{
-				valueType: valueType,
-			})
-			if err == nil {
-				correct++
+		for i := start; i < len(v); i++ {
+			err := e.validateAt(strconv.Itoa(i), v[i], schema)
+			if err != nil {
+				return err
 			}
-		}
 
-		if correct <= int(checkMax) {
-			return nil
+			e.addItem(i)
 		}
 
-		return NewError(int(checkMax), correct)
+		return nil
 	}, nil
 }
 
-func minContains(value any) (func(a any) *Error, error) {
-	if len(value.([]any)) < 2 {
-		return nil, errors.New("minContains requires 2 value")
-	}
-
+// contains counts the items matching its schema. At least minContains, 1 by
+// default, and at most maxContains of them must match, so minContains 0 turns
+// contains off. Both bounds are ignored without contains.
+func contains(c *compiler, value any) (validateFunc, error) {
 	v := value.([]any)
 
-	checkType, ok := v[1].(map[string]any)
-	if !ok {
-		return nil, errors.New("minContains requires type")
+	schema, err := c.compile(v[0])
+	if err != nil {
+		return nil, err
 	}
 
-	valueType, ok := getValueType(checkType["type"])
-	if !ok {
-		return nil, errors.New("minContains requires valid type")
+	checkMin, checkMax := 1, -1
+
+	if v[1] != nil {
+		checkMin, err = containsBound("minContains", v[1])
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	checkMin, ok := v[0].(float64)
-	if !ok {
-		return nil, errors.New("minContains requires number")
+	if v[2] != nil {
+		checkMax, err = containsBound("maxContains", v[2])
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return func(a any) *Error {
-		var correct int
+	return func(a any, e *evaluated) *Error {
+		matched := make([]int, 0)
+		causes := make([]*Error, 0, len(a.([]any)))
 
-		for _, elem := range a.([]any) {
-			err := validate(elem, &Schema{
-				valueType: valueType,
-			})
+		for i, elem := range a.([]any) {
+			err := e.validateAt(strconv.Itoa(i), elem, schema)
 			if err == nil {
-				correct++
+				matched = append(matched, i)
+				e.addItem(i)
 			}
-		}
 
-		if correct >= int(checkMin) {
-			return nil
+			causes = append(causes, err)
 		}
 
-		return NewError(int(checkMin), correct)
-	}, nil
-}
+		got := fmt.Sprintf("%d matched at %v", len(matched), matched)
 
-func contains(value any) (func(a any) *Error, error) {
-	v, ok := value.(map[string]any)
-	if !ok {
-		return nil, errors.New("contains requires type")
-	}
-
-	valueType, ok := getValueType(v["type"])
-	if !ok {
-		return nil, errors.New("contains requires valid type")
-	}
-
-	return func(a any) *Error {
-		types := make(map[string]struct{})
-		for _, elem := range a.([]any) {
-			types[reflect.ValueOf(elem).Kind().String()] = struct{}{}
+		if len(matched) < checkMin {
+			err := NewError(checkMin, got)
+			if len(matched) == 0 {
+				err.SetCauses(causes)
+			}
 
-			err := validate(elem, &Schema{
-				valueType: valueType,
-			})
-			if err == nil {
-				return nil
+			if v[1] != nil {
+				err.SetName("minContains")
 			}
 
+			return err
 		}
 
-		keys := make([]string, 0, len(types))
-		for vt := range types {
-			keys = append(keys, vt)
+		if checkMax >= 0 && len(matched) > checkMax {
+			return NewError(checkMax, got).SetName("maxContains")
 		}
 
-		return NewError(valueType, keys)
+		return nil
 	}, nil
 }
 
+// containsLegacy is contains before 2019-09, without minContains and
+// maxContains.
+func containsLegacy(c *compiler, value any) (validateFunc, error) {
+	return contains(c, []any{value, nil, nil})
+}
+
+func containsBound(name string, value any) (int, error) {
+	v, ok := value.(float64)
+	if !ok || float64(int(v)) != v || v < 0 {
+		return 0, errors.New(name + " requires non-negative integer")
+	}
+
+	return int(v), nil
+}
+
 func uniqueItems(value any) (func(a any) *Error, error) {
 	v, ok := value.(bool)
 	if !ok {
-		return nil, errors.New("uniqueItems requires *Errorean")
+		return nil, errors.New("uniqueItems requires boolean")
 	}
 
 	return func(a any) *Error {
@@ -270,14 +286,14 @@ func uniqueItems(value any) (func(a any) *Error, error) {
 			return nil
 		}
 
-		check := map[any]struct{}{}
-
-		for _, elem := range a.([]any) {
-			if _, ok := check[elem]; ok {
-				return NewError("unique", fmt.Sprintf("elem: %v duplicated", elem))
+		//arrays and objects are not hashable, so items are compared in pairs
+		items := a.([]any)
+		for i, elem := range items {
+			for _, other := range items[:i] {
+				if equal(elem, other) {
+					return NewError("unique", fmt.Sprintf("elem: %v duplicated", elem))
+				}
 			}
-
-			check[elem] = struct{}{}
 		}
 
 		return nil
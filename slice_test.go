@@ -0,0 +1,68 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		instance string
+		//err is a part of the expected error, empty for valid instances
+		err string
+	}{
+		{
+			name:     "contains",
+			schema:   `{"contains": {"type": "integer", "minimum": 2}}`,
+			instance: `["a", 1, 2]`,
+		},
+		{
+			name:     "contains failing shows what matched",
+			schema:   `{"contains": {"type": "integer", "minimum": 2}}`,
+			instance: `["a", 1]`,
+			err:      "failed to validate contains; got: 0 matched at [], expected: 1",
+		},
+		{
+			name:     "minContains",
+			schema:   `{"contains": {"type": "integer"}, "minContains": 2}`,
+			instance: `[1, "a"]`,
+			err:      "failed to validate minContains; got: 1 matched at [0], expected: 2",
+		},
+		{
+			name:     "maxContains",
+			schema:   `{"contains": {"type": "integer"}, "maxContains": 1}`,
+			instance: `[1, "a", 2]`,
+			err:      "failed to validate maxContains; got: 2 matched at [0 2], expected: 1",
+		},
+		{
+			name:     "minContains 0 turns contains off",
+			schema:   `{"contains": {"type": "integer"}, "minContains": 0}`,
+			instance: `["a"]`,
+		},
+		{
+			name:     "maxContains without contains is ignored",
+			schema:   `{"maxContains": 1}`,
+			instance: `[1, 2, 3]`,
+		},
+		{
+			name:     "minContains without contains is ignored",
+			schema:   `{"minContains": 2}`,
+			instance: `[]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.instance, tt.schema)
+
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("got %v, want valid", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
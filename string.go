@@ -1,131 +1,351 @@
 package jsonschema
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
-	"net"
-	"net/mail"
-	"net/url"
-	"regexp"
-	"time"
+	"mime"
+	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
-var stringValidation map[string]rawValidation = map[string]rawValidation{
-	"minLength": {
-		function: minLength,
-	},
-	"maxLength": {
-		function: maxLength,
-	},
-	"pattern": {
-		function: pattern,
-	},
-	"format": {
-		function: format,
-	},
+// stringValidationFor returns the string keywords of a dialect.
+// contentEncoding and contentMediaType exist since draft-07 and
+// contentSchema since 2019-09.
+func stringValidationFor(version int) map[string]rawValidation {
+	res := map[string]rawValidation{
+		"minLength": {
+			compile: minLength,
+		},
+		"maxLength": {
+			compile: maxLength,
+		},
+		"pattern": {
+			compile: pattern,
+		},
+		"format": {
+			compile: format,
+		},
+		"contentEncoding": {
+			compile: contentEncoding,
+		},
+		"contentMediaType": {
+			compile:  contentMediaType,
+			optional: []string{"contentEncoding"},
+		},
+		"contentSchema": {
+			compile:  contentSchema,
+			optional: []string{"contentMediaType", "contentEncoding"},
+		},
+	}
+
+	switch {
+	case version < 7:
+		delete(res, "contentEncoding")
+		delete(res, "contentMediaType")
+		fallthrough
+	case version < 2019:
+		delete(res, "contentSchema")
+	}
+
+	return res
 }
 
-func format(format any) (func(a any) *Error, error) {
-	format, ok := format.(string)
+// format checks the formats of the validator, the registered formats and the
+// formats that knownFormat knows, in that order, depending on the format mode
+// of the validator. A meta-schema whose $vocabulary makes format an
+// annotation turns FormatAssertion into FormatAnnotation, FormatStrict still
+// checks.
+func format(c *compiler, value any) (validateFunc, error) {
+	name, ok := value.(string)
 	if !ok {
 		return nil, errors.New("format requires string")
 	}
 
+	mode := c.validator.format
+	if mode == FormatAssertion && c.dialect.annotateFormat {
+		mode = FormatAnnotation
+	}
+
+	if mode == FormatAnnotation {
+		return noValidation, nil
+	}
+
+	validate, ok := customFormat(c.validator, name)
+	if !ok {
+		validate, ok = knownFormat(c.validator, name)
+	}
+
+	if !ok {
+		if mode == FormatStrict {
+			return nil, errors.New("unknown format " + name)
+		}
+
+		return noValidation, nil
+	}
+
+	return func(a any, _ *evaluated) *Error {
+		return validate(a)
+	}, nil
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = make(map[string]func(string) error)
+)
+
+// RegisterFormat adds a format for every validator. check returns an error
+// for values that are not valid; it replaces a built-in format of the same
+// name. Formats added with WithFormat take precedence.
+func RegisterFormat(name string, check func(string) error) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	formats[name] = check
+}
+
+func customFormat(v *Validator, name string) (func(a any) *Error, bool) {
+	check, ok := v.formats[name]
+	if !ok {
+		formatsMu.RLock()
+		check, ok = formats[name]
+		formatsMu.RUnlock()
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	return checkFormat(name, func(s string) bool {
+		return check(s) == nil
+	}), true
+}
+
+func knownFormat(v *Validator, format string) (func(a any) *Error, bool) {
 	switch format {
 	case "date-time":
-		return func(a any) *Error {
-			v := a.(string)
-			_, err := time.Parse(time.RFC3339, v)
-			if err != nil {
-				return NewError(format, a.(string))
-			}
-			return nil
-		}, nil
+		return checkFormat(format, isDateTime), true
 	case "date":
-		return func(a any) *Error {
-			v := a.(string)
-			_, err := time.Parse(time.DateOnly, v)
-			if err != nil {
-				return NewError(format, a.(string))
-			}
-			return nil
-		}, nil
+		return checkFormat(format, isDate), true
 	case "time":
-		return func(a any) *Error {
-			v := a.(string)
-			_, err := time.Parse(time.TimeOnly, v)
-			if err != nil {
-				return NewError(format, a.(string))
-			}
-			return nil
-		}, nil
+		return checkFormat(format, isTime), true
 	case "duration":
-		return func(a any) *Error {
-			_, err := time.ParseDuration(a.(string))
-			if err != nil {
-				return NewError(format, a.(string))
-			}
-			return nil
-		}, nil
+		return checkFormat(format, durationRegexp.MatchString), true
 	case "regex":
 		return func(a any) *Error {
-			_, err := regexp.Compile(a.(string))
+			_, err := compileRegex(v, a.(string))
 			if err != nil {
 				return NewError(format, a.(string))
 			}
 			return nil
-		}, nil
+		}, true
 	case "email":
+		return checkFormat(format, func(s string) bool {
+			return isEmail(s, false)
+		}), true
+	case "hostname":
+		return checkFormat(format, isHostname), true
+	case "uri":
+		return checkFormat(format, func(s string) bool {
+			absolute, ok := isURIReference(s, false)
+			return ok && absolute
+		}), true
+	case "ipv4":
+		return checkFormat(format, isIPv4), true
+	case "ipv6":
+		return checkFormat(format, isIPv6), true
+	case "uuid":
 		return func(a any) *Error {
-			_, err := mail.ParseAddress(a.(string))
+			_, err := uuid.Parse(a.(string))
 			if err != nil {
 				return NewError(format, a.(string))
 			}
 			return nil
-		}, nil
-	case "hostname", "uri":
-		return func(a any) *Error {
-			_, err := url.Parse(a.(string))
-			if err != nil {
-				return NewError(format, a.(string))
-			}
+		}, true
+	case "idn-email":
+		return checkFormat(format, func(s string) bool {
+			return isEmail(s, true)
+		}), true
+	case "idn-hostname":
+		return checkFormat(format, isIDNHostname), true
+	case "iri":
+		return checkFormat(format, func(s string) bool {
+			absolute, ok := isURIReference(s, true)
+			return ok && absolute
+		}), true
+	case "uri-reference":
+		return checkFormat(format, func(s string) bool {
+			_, ok := isURIReference(s, false)
+			return ok
+		}), true
+	case "iri-reference":
+		return checkFormat(format, func(s string) bool {
+			_, ok := isURIReference(s, true)
+			return ok
+		}), true
+	case "uri-template":
+		return checkFormat(format, isURITemplate), true
+	case "json-pointer":
+		return checkFormat(format, isJSONPointer), true
+	case "relative-json-pointer":
+		return checkFormat(format, isRelativeJSONPointer), true
+	}
+
+	return nil, false
+}
+
+// contentEncodings decode the contentEncoding values that are checked.
+// base64url accepts the encoding with and without padding.
+var contentEncodings = map[string]func(string) ([]byte, error){
+	"base64": base64.StdEncoding.DecodeString,
+	"base64url": func(s string) ([]byte, error) {
+		if strings.HasSuffix(s, "=") {
+			return base64.URLEncoding.DecodeString(s)
+		}
+
+		return base64.RawURLEncoding.DecodeString(s)
+	},
+	"hex": hex.DecodeString,
+}
+
+// contentEncoding checks that the string decodes. Like contentMediaType and
+// contentSchema it is an annotation unless the validator is created with
+// WithContentAssertion, and encodings that are not known are never checked.
+func contentEncoding(c *compiler, value any) (validateFunc, error) {
+	v, ok := value.(string)
+	if !ok {
+		return nil, errors.New("contentEncoding requires string")
+	}
+
+	decode, ok := contentEncodings[v]
+	if !ok || !c.validator.content {
+		return noValidation, nil
+	}
+
+	return func(a any, _ *evaluated) *Error {
+		_, err := decode(a.(string))
+		if err != nil {
+			return NewError(v, a.(string))
+		}
+
+		return nil
+	}, nil
+}
+
+func contentMediaType(c *compiler, value any) (validateFunc, error) {
+	v := value.([]any)
+
+	mediaType, ok := v[0].(string)
+	if !ok {
+		return nil, errors.New("contentMediaType requires string")
+	}
+
+	decode, ok := contentDecoder(v[1])
+	if !ok || !isJSONMediaType(mediaType) || !c.validator.content {
+		return noValidation, nil
+	}
+
+	return func(a any, _ *evaluated) *Error {
+		//contentEncoding reports content it can't decode
+		data, err := decode(a.(string))
+		if err != nil {
 			return nil
-		}, nil
-	case "ipv4", "ipv6":
-		return func(a any) *Error {
-			ip := net.ParseIP(a.(string))
-			if ip == nil {
-				return NewError(format, a.(string))
-			}
+		}
+
+		if !json.Valid(data) {
+			return NewError(mediaType, a.(string))
+		}
+
+		return nil
+	}, nil
+}
+
+// contentSchema validates the decoded JSON document. It is ignored without
+// contentMediaType.
+func contentSchema(c *compiler, value any) (validateFunc, error) {
+	v := value.([]any)
+
+	schema, err := c.compile(v[0])
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _ := v[1].(string)
+
+	decode, ok := contentDecoder(v[2])
+	if !ok || !isJSONMediaType(mediaType) || !c.validator.content {
+		return noValidation, nil
+	}
+
+	return func(a any, e *evaluated) *Error {
+		//contentEncoding and contentMediaType report content that isn't JSON
+		data, err := decode(a.(string))
+		if err != nil {
 			return nil
-		}, nil
-	case "uuid":
-		return func(a any) *Error {
-			_, err := uuid.Parse(a.(string))
-			if err != nil {
-				return NewError(format, a.(string))
-			}
+		}
+
+		var document any
+		if json.Unmarshal(data, &document) != nil {
 			return nil
-		}, nil
+		}
+
+		if err := e.validate(document, schema); err != nil {
+			return NewError("valid content", a.(string)).SetCauses([]*Error{err})
+		}
+
+		return nil
+	}, nil
+}
+
+// contentDecoder returns the decoder of a contentEncoding value, which is nil
+// for content that is not encoded, and false when the encoding is not known.
+func contentDecoder(value any) (func(string) ([]byte, error), bool) {
+	if value == nil {
+		return func(s string) ([]byte, error) {
+			return []byte(s), nil
+		}, true
 	}
 
-	return nil, errors.New("unknown format for str")
+	name, _ := value.(string)
+	decode, ok := contentEncodings[name]
+
+	return decode, ok
+}
+
+func isJSONMediaType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func noValidation(a any, _ *evaluated) *Error {
+	return nil
 }
 
-func pattern(pattern any) (func(a any) *Error, error) {
+func pattern(c *compiler, pattern any) (validateFunc, error) {
 	v, ok := pattern.(string)
 	if !ok {
 		return nil, errors.New("pattern requires string")
 	}
 
-	r, err := regexp.Compile(v)
+	r, err := compileRegex(c.validator, v)
 	if err != nil {
 		return nil, err
 	}
 
-	return func(a any) *Error {
-		ok := r.MatchString(a.(string))
+	return func(a any, _ *evaluated) *Error {
+		ok, err := r.match(a.(string))
+		if err != nil {
+			return NewError(r, err.Error())
+		}
+
 		if !ok {
 			return NewError(r, a.(string))
 		}
@@ -133,7 +353,7 @@ func pattern(pattern any) (func(a any) *Error, error) {
 	}, nil
 }
 
-func maxLength(max any) (func(a any) *Error, error) {
+func maxLength(c *compiler, max any) (validateFunc, error) {
 	v, ok := max.(float64)
 	if !ok {
 		return nil, errors.New("maxLength requires integer")
@@ -143,16 +363,18 @@ func maxLength(max any) (func(a any) *Error, error) {
 		return nil, errors.New("maxLength requires integer")
 	}
 
-	return func(a any) *Error {
-		if int(v) >= len(a.(string)) {
+	length := stringLength(c.validator)
+
+	return func(a any, _ *evaluated) *Error {
+		if int(v) >= length(a.(string)) {
 			return nil
 		}
 
-		return NewError(int(v), len(a.(string)))
+		return NewError(int(v), length(a.(string)))
 	}, nil
 }
 
-func minLength(min any) (func(a any) *Error, error) {
+func minLength(c *compiler, min any) (validateFunc, error) {
 	v, ok := min.(float64)
 	if !ok {
 		return nil, errors.New("minLength requires integer")
@@ -162,11 +384,23 @@ func minLength(min any) (func(a any) *Error, error) {
 		return nil, errors.New("minLength requires integer")
 	}
 
-	return func(a any) *Error {
-		if int(v) <= len(a.(string)) {
+	length := stringLength(c.validator)
+
+	return func(a any, _ *evaluated) *Error {
+		if int(v) <= length(a.(string)) {
 			return nil
 		}
 
-		return NewError(int(v), len(a.(string)))
+		return NewError(int(v), length(a.(string)))
 	}, nil
 }
+
+// stringLength returns how minLength and maxLength count: code points, as the
+// spec says, or grapheme clusters with WithGraphemeLength.
+func stringLength(v *Validator) func(string) int {
+	if v.graphemes {
+		return graphemeCount
+	}
+
+	return utf8.RuneCountInString
+}
@@ -0,0 +1,378 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// jsonString returns s as a JSON document, the form Validate takes instances
+// in.
+func jsonString(s string) string {
+	res, _ := json.Marshal(s)
+	return string(res)
+}
+
+func TestContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		instance string
+		valid    bool
+	}{
+		{
+			name:     "base64",
+			schema:   `{"contentEncoding": "base64"}`,
+			instance: "eyJhIjoxfQ==",
+			valid:    true,
+		},
+		{
+			name:     "base64 that fails to decode",
+			schema:   `{"contentEncoding": "base64"}`,
+			instance: "not base64!",
+		},
+		{
+			name:     "padded base64url",
+			schema:   `{"contentEncoding": "base64url"}`,
+			instance: "_-8=",
+			valid:    true,
+		},
+		{
+			name:     "unpadded base64url",
+			schema:   `{"contentEncoding": "base64url"}`,
+			instance: "_-8",
+			valid:    true,
+		},
+		{
+			name:     "base64url with the base64 alphabet",
+			schema:   `{"contentEncoding": "base64url"}`,
+			instance: "+/8=",
+		},
+		{
+			name:     "base64url with bad padding",
+			schema:   `{"contentEncoding": "base64url"}`,
+			instance: "_-8==",
+		},
+		{
+			name:     "hex",
+			schema:   `{"contentEncoding": "hex"}`,
+			instance: "7b7d",
+			valid:    true,
+		},
+		{
+			name:     "hex with a bad digit",
+			schema:   `{"contentEncoding": "hex"}`,
+			instance: "7g",
+		},
+		{
+			name:     "hex of odd length",
+			schema:   `{"contentEncoding": "hex"}`,
+			instance: "7b7",
+		},
+		{
+			name:     "unknown encoding",
+			schema:   `{"contentEncoding": "base32"}`,
+			instance: "not base32!",
+			valid:    true,
+		},
+		{
+			name:     "JSON",
+			schema:   `{"contentMediaType": "application/json"}`,
+			instance: `{"a": 1}`,
+			valid:    true,
+		},
+		{
+			name:     "not JSON",
+			schema:   `{"contentMediaType": "application/json"}`,
+			instance: `{"a": 1`,
+		},
+		{
+			name:     "not JSON under a +json media type",
+			schema:   `{"contentMediaType": "application/geo+json; charset=utf-8"}`,
+			instance: `{"a": 1`,
+		},
+		{
+			name:     "media type that is not checked",
+			schema:   `{"contentMediaType": "text/html"}`,
+			instance: `<p`,
+			valid:    true,
+		},
+		{
+			name:     "encoded content that is not JSON",
+			schema:   `{"contentEncoding": "base64", "contentMediaType": "application/json"}`,
+			instance: "bm90IGpzb24=",
+		},
+		{
+			name:     "content matching contentSchema",
+			schema:   `{"contentMediaType": "application/json", "contentSchema": {"required": ["a"]}}`,
+			instance: `{"a": 1}`,
+			valid:    true,
+		},
+		{
+			name:     "content failing contentSchema",
+			schema:   `{"contentMediaType": "application/json", "contentSchema": {"required": ["a"]}}`,
+			instance: `{"b": 1}`,
+		},
+		{
+			name:     "encoded content failing contentSchema",
+			schema:   `{"contentEncoding": "base64", "contentMediaType": "application/json", "contentSchema": {"required": ["a"]}}`,
+			instance: "eyJiIjoxfQ==",
+		},
+		{
+			name:     "contentSchema without contentMediaType",
+			schema:   `{"contentSchema": {"required": ["a"]}}`,
+			instance: `{"b": 1}`,
+			valid:    true,
+		},
+	}
+
+	v := NewValidator(WithContentAssertion())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(jsonString(tt.instance), tt.schema)
+			if (err == nil) != tt.valid {
+				t.Errorf("valid = %v, want %v: %v", err == nil, tt.valid, err)
+			}
+
+			//without WithContentAssertion the content keywords only annotate
+			err = NewValidator().Validate(jsonString(tt.instance), tt.schema)
+			if err != nil {
+				t.Errorf("default validator failed: %v", err)
+			}
+		})
+	}
+}
+
+// formatAnnotationMeta is a meta-schema that lists the format-annotation
+// vocabulary only.
+const formatAnnotationMeta = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"$vocabulary": {
+		"https://json-schema.org/draft/2020-12/vocab/core": true,
+		"https://json-schema.org/draft/2020-12/vocab/validation": true,
+		"https://json-schema.org/draft/2020-12/vocab/format-annotation": true
+	}
+}`
+
+func TestFormatMode(t *testing.T) {
+	meta := filepath.Join(t.TempDir(), "meta.json")
+	err := os.WriteFile(meta, []byte(formatAnnotationMeta), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	annotationSchema := func(format string) string {
+		return `{"$schema": "` + filepath.ToSlash(meta) + `", "format": "` + format + `"}`
+	}
+
+	tests := []struct {
+		name     string
+		mode     FormatMode
+		schema   string
+		instance string
+		//compileErr is a part of the error when the schema must not compile
+		compileErr string
+		valid      bool
+	}{
+		{
+			name:     "assertion checks known formats",
+			mode:     FormatAssertion,
+			schema:   `{"format": "ipv4"}`,
+			instance: `"1.2.3"`,
+		},
+		{
+			name:     "assertion ignores unknown formats",
+			mode:     FormatAssertion,
+			schema:   `{"format": "color"}`,
+			instance: `"1.2.3"`,
+			valid:    true,
+		},
+		{
+			name:     "annotation checks nothing",
+			mode:     FormatAnnotation,
+			schema:   `{"format": "ipv4"}`,
+			instance: `"1.2.3"`,
+			valid:    true,
+		},
+		{
+			name:     "annotation accepts unknown formats",
+			mode:     FormatAnnotation,
+			schema:   `{"format": "color"}`,
+			instance: `"1.2.3"`,
+			valid:    true,
+		},
+		{
+			name:     "strict checks known formats",
+			mode:     FormatStrict,
+			schema:   `{"format": "ipv4"}`,
+			instance: `"1.2.3"`,
+		},
+		{
+			name:       "strict rejects unknown formats",
+			mode:       FormatStrict,
+			schema:     `{"format": "color"}`,
+			instance:   `"1.2.3"`,
+			compileErr: "unknown format color",
+		},
+		{
+			name:     "format applies to strings only",
+			mode:     FormatStrict,
+			schema:   `{"format": "ipv4"}`,
+			instance: `4`,
+			valid:    true,
+		},
+		{
+			name:     "format-annotation vocabulary under assertion",
+			mode:     FormatAssertion,
+			schema:   annotationSchema("ipv4"),
+			instance: `"1.2.3"`,
+			valid:    true,
+		},
+		{
+			name:     "format-annotation vocabulary under strict checks known formats",
+			mode:     FormatStrict,
+			schema:   annotationSchema("ipv4"),
+			instance: `"1.2.3"`,
+		},
+		{
+			name:       "format-annotation vocabulary under strict rejects unknown formats",
+			mode:       FormatStrict,
+			schema:     annotationSchema("color"),
+			instance:   `"1.2.3"`,
+			compileErr: "unknown format color",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(WithFormatMode(tt.mode)).Validate(tt.instance, tt.schema)
+
+			switch {
+			case tt.compileErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.compileErr) {
+					t.Errorf("got %v, want %q", err, tt.compileErr)
+				}
+			case (err == nil) != tt.valid:
+				t.Errorf("valid = %v, want %v: %v", err == nil, tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestCustomFormat(t *testing.T) {
+	RegisterFormat("test-even", func(s string) error {
+		if len(s)%2 != 0 {
+			return errors.New("odd length")
+		}
+
+		return nil
+	})
+	RegisterFormat("ipv4", func(s string) error {
+		if s != "localhost" {
+			return errors.New("not localhost")
+		}
+
+		return nil
+	})
+	t.Cleanup(func() {
+		formatsMu.Lock()
+		defer formatsMu.Unlock()
+
+		delete(formats, "test-even")
+		delete(formats, "ipv4")
+	})
+
+	short := WithFormat("test-even", func(s string) error {
+		if len(s) > 2 {
+			return errors.New("too long")
+		}
+
+		return nil
+	})
+
+	tests := []struct {
+		name     string
+		options  []Option
+		format   string
+		instance string
+		//err is the expected error, empty for valid instances
+		err string
+	}{
+		{
+			name:     "registered format",
+			format:   "test-even",
+			instance: "ab",
+		},
+		{
+			name:     "registered format failing",
+			format:   "test-even",
+			instance: "abc",
+			err:      "failed to validate format; got: abc, expected: test-even",
+		},
+		{
+			name:     "registered format replaces a built-in one",
+			format:   "ipv4",
+			instance: "localhost",
+		},
+		{
+			name:     "registered format replacing a built-in one failing",
+			format:   "ipv4",
+			instance: "127.0.0.1",
+			err:      "failed to validate format; got: 127.0.0.1, expected: ipv4",
+		},
+		{
+			name:     "validator format takes precedence",
+			options:  []Option{short},
+			format:   "test-even",
+			instance: "a",
+		},
+		{
+			name:     "validator format failing",
+			options:  []Option{short},
+			format:   "test-even",
+			instance: "abcd",
+			err:      "failed to validate format; got: abcd, expected: test-even",
+		},
+		{
+			name:     "validator format replaces a built-in one",
+			options:  []Option{WithFormat("ipv4", func(string) error { return nil })},
+			format:   "ipv4",
+			instance: "not an address",
+		},
+		{
+			name:     "registered format under FormatAnnotation",
+			options:  []Option{WithFormatMode(FormatAnnotation)},
+			format:   "test-even",
+			instance: "abc",
+		},
+		{
+			name:     "validator format under FormatAnnotation",
+			options:  []Option{short, WithFormatMode(FormatAnnotation)},
+			format:   "test-even",
+			instance: "abcd",
+		},
+		{
+			name:     "custom formats are known to FormatStrict",
+			options:  []Option{WithFormatMode(FormatStrict)},
+			format:   "test-even",
+			instance: "ab",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(tt.options...).Validate(jsonString(tt.instance), `{"format": "`+tt.format+`"}`)
+
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("got %v, want valid", err)
+			case tt.err != "" && (err == nil || err.Error() != tt.err):
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package jsonschema
+
+import (
+	"sort"
+	"strconv"
+)
+
+// unevaluatedProperties applies to the properties that no other keyword of
+// the schema, including passing allOf, anyOf, oneOf, if, dependentSchemas and
+// $ref subschemas, evaluated.
+func unevaluatedProperties(c *compiler, value any) (validateFunc, error) {
+	schema, err := c.compile(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(a any, e *evaluated) *Error {
+		values, ok := a.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		names := make([]string, 0, len(values))
+		for name := range values {
+			if _, ok := e.properties[name]; !ok {
+				names = append(names, name)
+			}
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			if schema.falseSchema {
+				return NewError("no unevaluated properties", name)
+			}
+
+			err := e.validateAt(name, values[name], schema)
+			if err != nil {
+				return NewError("valid unevaluated property", name).SetCauses([]*Error{err})
+			}
+		}
+
+		for _, name := range names {
+			e.addProperty(name)
+		}
+
+		return nil
+	}, nil
+}
+
+// unevaluatedItems applies to the array items that no other keyword of the
+// schema or its passing in-place subschemas evaluated.
+func unevaluatedItems(c *compiler, value any) (validateFunc, error) {
+	schema, err := c.compile(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(a any, e *evaluated) *Error {
+		values, ok := a.([]any)
+		if !ok {
+			return nil
+		}
+
+		for i, value := range values {
+			if _, ok := e.items[i]; ok {
+				continue
+			}
+
+			if schema.falseSchema {
+				return NewError("no unevaluated items", i)
+			}
+
+			err := e.validateAt(strconv.Itoa(i), value, schema)
+			if err != nil {
+				return NewError("valid unevaluated item", i).SetCauses([]*Error{err})
+			}
+		}
+
+		for i := range values {
+			e.addItem(i)
+		}
+
+		return nil
+	}, nil
+}
@@ -0,0 +1,154 @@
+package jsonschema
+
+import "reflect"
+
+// Validator validates instances against schemas with its own settings. The
+// zero value is not ready to use, create one with NewValidator.
+type Validator struct {
+	dialect   Dialect
+	content   bool
+	format    FormatMode
+	formats   map[string]func(string) error
+	keywords  map[string]customKeyword
+	graphemes bool
+	regex     RegexEngine
+}
+
+// FormatMode decides what the format keyword does.
+type FormatMode int
+
+const (
+	// FormatAssertion checks the formats the validator knows and ignores
+	// the others. It is the default. Schemas whose meta-schema lists the
+	// format-annotation vocabulary but no format assertion vocabulary in
+	// $vocabulary are treated as FormatAnnotation.
+	FormatAssertion FormatMode = iota
+	// FormatAnnotation treats every format as an annotation and checks
+	// nothing, which is the default of the 2019-09 and 2020-12 specs.
+	FormatAnnotation
+	// FormatStrict checks the known formats and fails to compile schemas
+	// with other formats, whatever the $vocabulary of the meta-schema says.
+	FormatStrict
+)
+
+// RegexEngine decides how the patterns of pattern, patternProperties and the
+// regex format are read.
+type RegexEngine int
+
+const (
+	// RegexRE2 uses the regexp package. It runs in linear time but rejects
+	// lookarounds and backreferences, and reads some ECMA-262 patterns
+	// differently, like \s which only matches ASCII spaces. It is the
+	// default.
+	RegexRE2 RegexEngine = iota
+	// RegexECMA reads patterns as ECMA-262 regular expressions with the u
+	// flag, as the spec asks. It backtracks with a limited budget of steps
+	// and memory: a match that would take longer, like nested quantifiers on
+	// some strings or repeats of groups over very long strings, fails the
+	// keyword with "backtracking limit exceeded" instead.
+	RegexECMA
+)
+
+type Option func(*Validator)
+
+// WithDialect sets the dialect of schema documents that don't declare one
+// with $schema. By default they follow Draft2020 but also keep the draft-07
+// dependencies, array form items and additionalItems, so legacy schemas keep
+// their meaning. WithDialect(Draft2020) drops them: dependencies is ignored
+// like any unknown keyword and the array form of items fails to compile.
+func WithDialect(dialect Dialect) Option {
+	return func(v *Validator) {
+		v.dialect = dialect
+	}
+}
+
+// WithContentAssertion makes contentEncoding, contentMediaType and
+// contentSchema assertions. Strings encoded with base64, base64url or hex are
+// decoded, and JSON content is parsed and validated against contentSchema.
+func WithContentAssertion() Option {
+	return func(v *Validator) {
+		v.content = true
+	}
+}
+
+// WithGraphemeLength makes minLength and maxLength count grapheme clusters,
+// the characters a user sees, instead of code points. "e" followed by a
+// combining accent or a flag emoji is one grapheme cluster but two code
+// points.
+func WithGraphemeLength() Option {
+	return func(v *Validator) {
+		v.graphemes = true
+	}
+}
+
+// WithFormatMode sets how the format keyword is handled.
+func WithFormatMode(mode FormatMode) Option {
+	return func(v *Validator) {
+		v.format = mode
+	}
+}
+
+// WithRegexEngine sets the engine of pattern, patternProperties and the regex
+// format. Schemas with patterns the engine can't support fail to compile.
+func WithRegexEngine(engine RegexEngine) Option {
+	return func(v *Validator) {
+		v.regex = engine
+	}
+}
+
+// WithFormat adds a format to the validator. check returns an error for
+// values that are not valid; the format takes precedence over built-in and
+// registered formats of the same name.
+func WithFormat(name string, check func(string) error) Option {
+	return func(v *Validator) {
+		if v.formats == nil {
+			v.formats = make(map[string]func(string) error)
+		}
+
+		v.formats[name] = check
+	}
+}
+
+// WithKeyword adds a custom keyword to the validator, see RegisterKeyword. It
+// takes precedence over a registered keyword of the same name.
+func WithKeyword(name string, keyword Keyword, valueTypes ...ValueType) Option {
+	return func(v *Validator) {
+		if v.keywords == nil {
+			v.keywords = make(map[string]customKeyword)
+		}
+
+		v.keywords[name] = customKeyword{
+			keyword:    keyword,
+			valueTypes: valueTypes,
+		}
+	}
+}
+
+func NewValidator(options ...Option) *Validator {
+	res := &Validator{}
+
+	for _, option := range options {
+		option(res)
+	}
+
+	return res
+}
+
+func (v *Validator) Validate(target any, schema any) error {
+	validatedTarget, err := validateTarget(reflect.ValueOf(target))
+	if err != nil {
+		return err
+	}
+
+	validatedSchema, err := validateSchema(v, reflect.ValueOf(schema))
+	if err != nil {
+		return err
+	}
+
+	err = validate(validatedTarget, validatedSchema)
+	if err != (*Error)(nil) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package jsonschema
+
+import (
+	"errors"
+	"sort"
+)
+
+// vocabularies maps the URI of each known vocabulary to the keywords it
+// enables. A meta-schema with $vocabulary enables only the keywords of the
+// vocabularies it lists; the core keywords, like $ref, are always enabled.
+var vocabularies = map[string][]string{
+	"https://json-schema.org/draft/2020-12/vocab/core": {
+		"$id", "$schema", "$ref", "$anchor", "$dynamicRef", "$dynamicAnchor", "$vocabulary", "$comment", "$defs",
+	},
+	"https://json-schema.org/draft/2020-12/vocab/applicator": {
+		"prefixItems", "items", "contains", "additionalProperties", "properties", "patternProperties",
+		"dependentSchemas", "propertyNames", "if", "then", "else", "allOf", "anyOf", "oneOf", "not",
+	},
+	"https://json-schema.org/draft/2020-12/vocab/unevaluated": {
+		"unevaluatedItems", "unevaluatedProperties",
+	},
+	"https://json-schema.org/draft/2020-12/vocab/validation": {
+		"type", "const", "enum", "multipleOf", "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+		"maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems", "maxContains", "minContains",
+		"maxProperties", "minProperties", "required", "dependentRequired",
+	},
+	"https://json-schema.org/draft/2020-12/vocab/meta-data": {
+		"title", "description", "default", "deprecated", "readOnly", "writeOnly", "examples",
+	},
+	"https://json-schema.org/draft/2020-12/vocab/format-annotation": {
+		"format",
+	},
+	"https://json-schema.org/draft/2020-12/vocab/format-assertion": {
+		"format",
+	},
+	"https://json-schema.org/draft/2020-12/vocab/content": {
+		"contentEncoding", "contentMediaType", "contentSchema",
+	},
+	"https://json-schema.org/draft/2019-09/vocab/core": {
+		"$id", "$schema", "$ref", "$anchor", "$recursiveRef", "$recursiveAnchor", "$vocabulary", "$comment", "$defs",
+	},
+	"https://json-schema.org/draft/2019-09/vocab/applicator": {
+		"additionalItems", "unevaluatedItems", "items", "contains", "additionalProperties", "unevaluatedProperties",
+		"properties", "patternProperties", "dependentSchemas", "propertyNames", "if", "then", "else",
+		"allOf", "anyOf", "oneOf", "not",
+	},
+	"https://json-schema.org/draft/2019-09/vocab/validation": {
+		"type", "const", "enum", "multipleOf", "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+		"maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems", "maxContains", "minContains",
+		"maxProperties", "minProperties", "required", "dependentRequired",
+	},
+	"https://json-schema.org/draft/2019-09/vocab/meta-data": {
+		"title", "description", "default", "deprecated", "readOnly", "writeOnly", "examples",
+	},
+	"https://json-schema.org/draft/2019-09/vocab/format": {
+		"format",
+	},
+	"https://json-schema.org/draft/2019-09/vocab/content": {
+		"contentEncoding", "contentMediaType", "contentSchema",
+	},
+}
+
+// formatAssertion lists the vocabularies that make format an assertion. With
+// only the 2020-12 format-annotation vocabulary format is not checked.
+var formatAssertion = map[string]bool{
+	"https://json-schema.org/draft/2020-12/vocab/format-assertion": true,
+	"https://json-schema.org/draft/2019-09/vocab/format":           true,
+}
+
+// vocabularyDialect returns the dialect of a meta-schema that declares
+// $vocabulary: the keywords of base limited to the listed vocabularies. An
+// unknown vocabulary fails unless it is listed as optional with false.
+func vocabularyDialect(base *dialect, value any) (*dialect, error) {
+	values, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("$vocabulary requires object")
+	}
+
+	uris := make([]string, 0, len(values))
+	for uri := range values {
+		uris = append(uris, uri)
+	}
+
+	sort.Strings(uris)
+
+	keywords := make(map[string]struct{})
+	var assertFormat bool
+
+	for _, uri := range uris {
+		required, ok := values[uri].(bool)
+		if !ok {
+			return nil, errors.New("$vocabulary requires boolean for " + uri)
+		}
+
+		names, ok := vocabularies[uri]
+		if !ok {
+			if required {
+				return nil, errors.New("unknown required vocabulary " + uri)
+			}
+
+			continue
+		}
+
+		for _, name := range names {
+			keywords[name] = struct{}{}
+		}
+
+		assertFormat = assertFormat || formatAssertion[uri]
+	}
+
+	res := &dialect{
+		version:        base.version,
+		id:             base.id,
+		keywords:       keywords,
+		annotateFormat: !assertFormat,
+		validations:    make(map[ValueType]map[string]rawValidation),
+		common:         filterValidation(base.common, keywords),
+		final:          filterValidation(base.final, keywords),
+	}
+
+	for valueType, validation := range base.validations {
+		res.validations[valueType] = filterValidation(validation, keywords)
+	}
+
+	return res, nil
+}
+
+func filterValidation(validation map[string]rawValidation, keywords map[string]struct{}) map[string]rawValidation {
+	if validation == nil {
+		return nil
+	}
+
+	res := make(map[string]rawValidation)
+
+	for name, value := range validation {
+		if _, ok := keywords[name]; ok {
+			res[name] = value
+		}
+	}
+
+	return res
+}